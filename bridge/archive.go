@@ -0,0 +1,348 @@
+package bridge
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+type archiveSource struct {
+	path string // absolute path on disk
+	name string // slash separated name inside the archive
+	info fs.FileInfo
+	link string // symlink target
+}
+
+type archiveWriter interface {
+	add(src archiveSource, r io.Reader) error
+	close() error
+}
+
+func (a *App) ZipFiles(paths []string, output string, options ArchiveOptions) FlagResult {
+	log.Printf("ZipFiles: %v -> %s %v", paths, output, options)
+
+	if err := writeArchive(a, paths, output, options, newZipArchiveWriter); err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	return FlagResult{true, "Success"}
+}
+
+func (a *App) TarGzFiles(paths []string, output string, options ArchiveOptions) FlagResult {
+	log.Printf("TarGzFiles: %v -> %s %v", paths, output, options)
+
+	if err := writeArchive(a, paths, output, options, newTarGzArchiveWriter); err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	return FlagResult{true, "Success"}
+}
+
+func writeArchive(a *App, paths []string, output string, options ArchiveOptions, newWriter func(w io.Writer) archiveWriter) error {
+	outputPath, err := sandboxPath(output, sandboxWrite)
+	if err != nil {
+		return err
+	}
+
+	sources, err := collectArchiveSources(paths, outputPath, options)
+	if err != nil {
+		return err
+	}
+	if len(sources) == 0 {
+		return errors.New("no files to archive")
+	}
+
+	var total int64
+	for _, src := range sources {
+		if src.info.Mode().IsRegular() {
+			total += src.info.Size()
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(outputPath), os.ModePerm); err != nil {
+		return err
+	}
+
+	// Build the archive next to its destination so a failed run never leaves half of it behind.
+	tmp, err := os.CreateTemp(filepath.Dir(outputPath), "."+filepath.Base(outputPath)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	cleanup := func(err error) error {
+		tmp.Close()
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	var tracker io.Writer
+	if options.Event != "" {
+		tracker = &WriteTracker{Total: total, EmitThreshold: 128 * 1024, ProgressChange: options.Event, App: a}
+	}
+
+	w := newWriter(tmp)
+	manifest := []string{}
+
+	for _, src := range sources {
+		if !src.info.Mode().IsRegular() {
+			if err := w.add(src, nil); err != nil {
+				return cleanup(err)
+			}
+			continue
+		}
+
+		f, err := os.Open(src.path)
+		if err != nil {
+			return cleanup(err)
+		}
+
+		var r io.Reader = f
+		if tracker != nil {
+			r = io.TeeReader(r, tracker)
+		}
+		hash := sha256.New()
+		if options.Manifest != "" {
+			r = io.TeeReader(r, hash)
+		}
+
+		err = w.add(src, r)
+		f.Close()
+		if err != nil {
+			return cleanup(err)
+		}
+
+		if options.Manifest != "" {
+			manifest = append(manifest, hex.EncodeToString(hash.Sum(nil))+"  "+src.name)
+		}
+	}
+
+	if options.Manifest != "" {
+		content := strings.Join(manifest, "\n") + "\n"
+		src := archiveSource{
+			name: options.Manifest,
+			info: archiveFileInfo{name: filepath.Base(options.Manifest), size: int64(len(content)), mode: 0644, modTime: time.Now()},
+		}
+		if err := w.add(src, strings.NewReader(content)); err != nil {
+			return cleanup(err)
+		}
+	}
+
+	if err := w.close(); err != nil {
+		return cleanup(err)
+	}
+	if err := tmp.Close(); err != nil {
+		return cleanup(err)
+	}
+	// CreateTemp makes the file private, archives get the mode of any other created file.
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		return cleanup(err)
+	}
+
+	return os.Rename(tmpPath, outputPath)
+}
+
+// collectArchiveSources expands directories and applies the filters. Entries are named
+// relative to options.Base, or to the parent of each given path so directories keep their name.
+func collectArchiveSources(paths []string, outputPath string, options ArchiveOptions) ([]archiveSource, error) {
+	base := ""
+	if options.Base != "" {
+		var err error
+		if base, err = sandboxPath(options.Base, sandboxRead); err != nil {
+			return nil, err
+		}
+	}
+
+	seen := map[string]bool{}
+	sources := []archiveSource{}
+
+	for _, p := range paths {
+		policy, root, err := sandboxResolve(p, sandboxRead)
+		if err != nil {
+			return nil, err
+		}
+		rootBase := base
+		if rootBase == "" {
+			rootBase = filepath.ToSlash(filepath.Dir(root))
+		}
+
+		err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			path = filepath.ToSlash(path)
+			if path == outputPath || seen[path] {
+				return nil
+			}
+
+			rel, err := filepath.Rel(rootBase, path)
+			if err != nil || rel == ".." || strings.HasPrefix(filepath.ToSlash(rel), "../") {
+				return errors.New(path + " is outside of " + rootBase)
+			}
+			name := filepath.ToSlash(rel)
+			if name == "." {
+				return nil
+			}
+
+			if matchPathGlob(options.Exclude, name) || policy.hides(path) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.IsDir() && len(options.Include) > 0 && !matchPathGlob(options.Include, name) {
+				return nil
+			}
+
+			info, err := os.Lstat(path)
+			if err != nil {
+				return err
+			}
+
+			src := archiveSource{path: path, name: name, info: info}
+			if info.Mode()&os.ModeSymlink != 0 {
+				if src.link, err = os.Readlink(path); err != nil {
+					return err
+				}
+			} else if !info.IsDir() && !info.Mode().IsRegular() {
+				// Sockets, devices and pipes have no place in an archive.
+				return nil
+			}
+
+			seen[path] = true
+			sources = append(sources, src)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Drop directories that ended up empty because all their files were filtered out.
+	if len(options.Include) > 0 {
+		used := map[string]bool{}
+		for _, src := range sources {
+			if !src.info.IsDir() {
+				for dir := filepath.ToSlash(filepath.Dir(src.name)); dir != "." && dir != "/"; dir = filepath.ToSlash(filepath.Dir(dir)) {
+					used[dir] = true
+				}
+			}
+		}
+		filtered := sources[:0]
+		for _, src := range sources {
+			if !src.info.IsDir() || used[src.name] {
+				filtered = append(filtered, src)
+			}
+		}
+		sources = filtered
+	}
+
+	sort.SliceStable(sources, func(i, j int) bool { return sources[i].name < sources[j].name })
+
+	return sources, nil
+}
+
+type zipArchiveWriter struct {
+	zw *zip.Writer
+}
+
+func newZipArchiveWriter(w io.Writer) archiveWriter {
+	return &zipArchiveWriter{zip.NewWriter(w)}
+}
+
+func (z *zipArchiveWriter) add(src archiveSource, r io.Reader) error {
+	header, err := zip.FileInfoHeader(src.info)
+	if err != nil {
+		return err
+	}
+	header.Name = src.name
+
+	switch {
+	case src.info.IsDir():
+		header.Name += "/"
+		header.Method = zip.Store
+	case src.link != "":
+		header.Method = zip.Store
+		r = strings.NewReader(src.link)
+	default:
+		header.Method = zip.Deflate
+	}
+
+	w, err := z.zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+
+	if r != nil {
+		_, err = io.Copy(w, r)
+	}
+	return err
+}
+
+func (z *zipArchiveWriter) close() error {
+	return z.zw.Close()
+}
+
+type tarGzArchiveWriter struct {
+	gw *gzip.Writer
+	tw *tar.Writer
+}
+
+func newTarGzArchiveWriter(w io.Writer) archiveWriter {
+	gw := gzip.NewWriter(w)
+	return &tarGzArchiveWriter{gw, tar.NewWriter(gw)}
+}
+
+func (t *tarGzArchiveWriter) add(src archiveSource, r io.Reader) error {
+	header, err := tar.FileInfoHeader(src.info, src.link)
+	if err != nil {
+		return err
+	}
+	header.Name = src.name
+	if src.info.IsDir() {
+		header.Name += "/"
+	}
+
+	if err := t.tw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	if r != nil && header.Typeflag == tar.TypeReg {
+		_, err = io.Copy(t.tw, r)
+	}
+	return err
+}
+
+func (t *tarGzArchiveWriter) close() error {
+	if err := t.tw.Close(); err != nil {
+		return err
+	}
+	return t.gw.Close()
+}
+
+// archiveFileInfo describes entries generated in memory, like the manifest.
+type archiveFileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (fi archiveFileInfo) Name() string       { return fi.name }
+func (fi archiveFileInfo) Size() int64        { return fi.size }
+func (fi archiveFileInfo) Mode() fs.FileMode  { return fi.mode }
+func (fi archiveFileInfo) ModTime() time.Time { return fi.modTime }
+func (fi archiveFileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi archiveFileInfo) Sys() any           { return nil }
@@ -0,0 +1,164 @@
+package bridge
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+type fileBackup struct {
+	Generation int    `json:"generation"`
+	Path       string `json:"path"`
+	Size       int64  `json:"size"`
+	ModTime    int64  `json:"modTime"`
+}
+
+func (a *App) ListBackups(path string) FlagResult {
+	log.Printf("ListBackups: %s", path)
+
+	fullPath, err := sandboxPath(path, sandboxRead)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	backups := []fileBackup{}
+	for generation := 1; ; generation++ {
+		backupPath := backupName(fullPath, generation)
+		stat, err := os.Stat(backupPath)
+		if err != nil {
+			break
+		}
+		backups = append(backups, fileBackup{generation, backupPath, stat.Size(), stat.ModTime().UnixMilli()})
+	}
+
+	b, err := json.Marshal(backups)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	return FlagResult{true, string(b)}
+}
+
+func (a *App) RestoreBackup(path string, generation int) FlagResult {
+	log.Printf("RestoreBackup: %s %d", path, generation)
+
+	if generation <= 0 {
+		return FlagResult{false, "invalid backup generation"}
+	}
+
+	fullPath, err := sandboxPath(path, sandboxWrite)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	data, err := os.ReadFile(backupName(fullPath, generation))
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	if err := writeFileAtomic(fullPath, data); err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	return FlagResult{true, "Success"}
+}
+
+// backupName returns the path of a backup generation, 1 being the newest.
+func backupName(path string, generation int) string {
+	return path + "." + strconv.Itoa(generation) + ".bak"
+}
+
+// rotateBackups shifts the existing backups of path by one generation and copies
+// the current file into the newest one.
+func rotateBackups(path string, generations int) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(backupName(path, generations)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for i := generations - 1; i >= 1; i-- {
+		if err := os.Rename(backupName(path, i), backupName(path, i+1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	return writeFileAtomic(backupName(path, 1), data)
+}
+
+// writeFileAtomic replaces path so that it holds either the old or the new content
+// even when the system crashes halfway: the data is written to a temporary file in
+// the same directory, flushed to disk and renamed over the target.
+func writeFileAtomic(path string, data []byte) error {
+	// Replace the file a symlink points to, not the link itself.
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+
+	perm := os.FileMode(0644)
+	if stat, err := os.Stat(path); err == nil {
+		perm = stat.Mode().Perm()
+	}
+
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	cleanup := func(err error) error {
+		tmp.Close()
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		return cleanup(err)
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		return cleanup(err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return cleanup(err)
+	}
+	if err := tmp.Close(); err != nil {
+		return cleanup(err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	return syncDir(dir)
+}
+
+// syncDir makes a rename durable. Windows cannot open directories for syncing
+// and commits renames on its own.
+func syncDir(dir string) error {
+	if Env.OS == "windows" {
+		return nil
+	}
+
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("sync %s: %w", dir, err)
+	}
+
+	return nil
+}
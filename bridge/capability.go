@@ -0,0 +1,49 @@
+package bridge
+
+import (
+	"encoding/json"
+	"log"
+)
+
+type fileCapabilities struct {
+	Permitted   []string `json:"permitted"`
+	Inheritable []string `json:"inheritable"`
+	Effective   bool     `json:"effective"`
+	CanSet      bool     `json:"canSet"`
+}
+
+func (a *App) GetFileCapabilities(path string) FlagResult {
+	log.Printf("GetFileCapabilities: %s", path)
+
+	fullPath, err := sandboxPath(path, sandboxRead)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	caps, err := readFileCapabilities(fullPath)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	b, err := json.Marshal(caps)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	return FlagResult{true, string(b)}
+}
+
+func (a *App) SetFileCapabilities(path string, capabilities []string) FlagResult {
+	log.Printf("SetFileCapabilities: %s %v", path, capabilities)
+
+	fullPath, err := sandboxPath(path, sandboxWrite)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	if err := writeFileCapabilities(fullPath, capabilities); err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	return FlagResult{true, "Success"}
+}
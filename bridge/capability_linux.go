@@ -0,0 +1,293 @@
+//go:build linux
+
+package bridge
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	vfsCapRevisionMask = 0xff000000
+	vfsCapRevision1    = 0x01000000
+	vfsCapRevision2    = 0x02000000
+	vfsCapRevision3    = 0x03000000
+	vfsCapEffective    = 0x000001
+	vfsCapXattr        = "security.capability"
+)
+
+var capabilityBits = map[string]uint{
+	"CAP_CHOWN":            unix.CAP_CHOWN,
+	"CAP_DAC_OVERRIDE":     unix.CAP_DAC_OVERRIDE,
+	"CAP_DAC_READ_SEARCH":  unix.CAP_DAC_READ_SEARCH,
+	"CAP_FOWNER":           unix.CAP_FOWNER,
+	"CAP_KILL":             unix.CAP_KILL,
+	"CAP_SETGID":           unix.CAP_SETGID,
+	"CAP_SETUID":           unix.CAP_SETUID,
+	"CAP_SETPCAP":          unix.CAP_SETPCAP,
+	"CAP_NET_BIND_SERVICE": unix.CAP_NET_BIND_SERVICE,
+	"CAP_NET_BROADCAST":    unix.CAP_NET_BROADCAST,
+	"CAP_NET_ADMIN":        unix.CAP_NET_ADMIN,
+	"CAP_NET_RAW":          unix.CAP_NET_RAW,
+	"CAP_IPC_LOCK":         unix.CAP_IPC_LOCK,
+	"CAP_SYS_CHROOT":       unix.CAP_SYS_CHROOT,
+	"CAP_SYS_PTRACE":       unix.CAP_SYS_PTRACE,
+	"CAP_SYS_ADMIN":        unix.CAP_SYS_ADMIN,
+	"CAP_SYS_NICE":         unix.CAP_SYS_NICE,
+	"CAP_SYS_RESOURCE":     unix.CAP_SYS_RESOURCE,
+	"CAP_SYS_TIME":         unix.CAP_SYS_TIME,
+	"CAP_SETFCAP":          unix.CAP_SETFCAP,
+	"CAP_BPF":              unix.CAP_BPF,
+}
+
+// applyCredential switches the child to another user and raises ambient capabilities,
+// so a core can manage TUN devices without the whole GUI running as root.
+func applyCredential(cmd *exec.Cmd, options CredentialOptions) error {
+	if options.User == "" && options.Group == "" && len(options.Capabilities) == 0 {
+		return nil
+	}
+
+	caps, err := parseCapabilities(options.Capabilities)
+	if err != nil {
+		return err
+	}
+
+	permitted, effective, err := processCapabilities()
+	if err != nil {
+		return err
+	}
+
+	// Ambient capabilities can only be passed on when we hold them ourselves.
+	var missing []string
+	for _, bit := range caps {
+		if permitted&(1<<bit) == 0 {
+			missing = append(missing, capabilityName(bit))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("cannot grant %s: the GUI does not hold these capabilities, run it as root or grant them to the GUI binary", strings.Join(missing, ", "))
+	}
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+
+	if options.User != "" || options.Group != "" {
+		credential, err := lookupCredential(options.User, options.Group)
+		if err != nil {
+			return err
+		}
+
+		if credential.Uid != uint32(os.Getuid()) && effective&(1<<unix.CAP_SETUID) == 0 {
+			return errors.New("cannot switch user: the GUI lacks CAP_SETUID")
+		}
+		if (credential.Gid != uint32(os.Getgid()) || !credential.NoSetGroups) && effective&(1<<unix.CAP_SETGID) == 0 {
+			return errors.New("cannot switch group: the GUI lacks CAP_SETGID")
+		}
+
+		cmd.SysProcAttr.Credential = credential
+	}
+
+	for _, bit := range caps {
+		cmd.SysProcAttr.AmbientCaps = append(cmd.SysProcAttr.AmbientCaps, uintptr(bit))
+	}
+
+	return nil
+}
+
+func lookupCredential(userName string, groupName string) (*syscall.Credential, error) {
+	credential := &syscall.Credential{
+		Uid:         uint32(os.Getuid()),
+		Gid:         uint32(os.Getgid()),
+		NoSetGroups: true,
+	}
+
+	if userName != "" {
+		u, err := user.Lookup(userName)
+		if err != nil {
+			if u, err = user.LookupId(userName); err != nil {
+				return nil, fmt.Errorf("unknown user: %s", userName)
+			}
+		}
+
+		uid, _ := strconv.ParseUint(u.Uid, 10, 32)
+		gid, _ := strconv.ParseUint(u.Gid, 10, 32)
+		credential.Uid = uint32(uid)
+		credential.Gid = uint32(gid)
+
+		// Take on the user's supplementary groups as well, not the GUI's.
+		if ids, err := u.GroupIds(); err == nil {
+			credential.NoSetGroups = false
+			for _, id := range ids {
+				if n, err := strconv.ParseUint(id, 10, 32); err == nil {
+					credential.Groups = append(credential.Groups, uint32(n))
+				}
+			}
+		}
+	}
+
+	if groupName != "" {
+		g, err := user.LookupGroup(groupName)
+		if err != nil {
+			if g, err = user.LookupGroupId(groupName); err != nil {
+				return nil, fmt.Errorf("unknown group: %s", groupName)
+			}
+		}
+
+		gid, _ := strconv.ParseUint(g.Gid, 10, 32)
+		credential.Gid = uint32(gid)
+	}
+
+	// Staying who we are keeps the current groups, setgroups would need CAP_SETGID.
+	if credential.Uid == uint32(os.Getuid()) && credential.Gid == uint32(os.Getgid()) {
+		credential.NoSetGroups = true
+		credential.Groups = nil
+	}
+
+	return credential, nil
+}
+
+func parseCapabilities(names []string) ([]uint, error) {
+	bits := []uint{}
+	for _, name := range names {
+		key := strings.ToUpper(strings.TrimSpace(name))
+		if !strings.HasPrefix(key, "CAP_") {
+			key = "CAP_" + key
+		}
+		bit, ok := capabilityBits[key]
+		if !ok {
+			return nil, fmt.Errorf("unknown capability: %s", name)
+		}
+		if !slices.Contains(bits, bit) {
+			bits = append(bits, bit)
+		}
+	}
+	return bits, nil
+}
+
+func capabilityName(bit uint) string {
+	for name, b := range capabilityBits {
+		if b == bit {
+			return name
+		}
+	}
+	return "CAP_" + strconv.FormatUint(uint64(bit), 10)
+}
+
+func capabilityNames(mask uint64) []string {
+	names := []string{}
+	for bit := uint(0); bit < 64; bit++ {
+		if mask&(1<<bit) != 0 {
+			names = append(names, capabilityName(bit))
+		}
+	}
+	return names
+}
+
+func processCapabilities() (permitted uint64, effective uint64, err error) {
+	hdr := unix.CapUserHeader{Version: unix.LINUX_CAPABILITY_VERSION_3}
+	var data [2]unix.CapUserData
+	if err := unix.Capget(&hdr, &data[0]); err != nil {
+		return 0, 0, err
+	}
+
+	permitted = uint64(data[0].Permitted) | uint64(data[1].Permitted)<<32
+	effective = uint64(data[0].Effective) | uint64(data[1].Effective)<<32
+	return permitted, effective, nil
+}
+
+func readFileCapabilities(path string) (*fileCapabilities, error) {
+	caps := &fileCapabilities{Permitted: []string{}, Inheritable: []string{}}
+
+	if _, effective, err := processCapabilities(); err == nil {
+		caps.CanSet = effective&(1<<unix.CAP_SETFCAP) != 0
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 24)
+	n, err := unix.Getxattr(path, vfsCapXattr, buf)
+	if errors.Is(err, unix.ENODATA) {
+		return caps, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	buf = buf[:n]
+
+	if len(buf) < 12 {
+		return nil, errors.New("invalid file capability data")
+	}
+
+	magic := binary.LittleEndian.Uint32(buf[0:4])
+	permitted := uint64(binary.LittleEndian.Uint32(buf[4:8]))
+	inheritable := uint64(binary.LittleEndian.Uint32(buf[8:12]))
+
+	switch magic & vfsCapRevisionMask {
+	case vfsCapRevision1:
+	case vfsCapRevision2, vfsCapRevision3:
+		if len(buf) < 20 {
+			return nil, errors.New("invalid file capability data")
+		}
+		permitted |= uint64(binary.LittleEndian.Uint32(buf[12:16])) << 32
+		inheritable |= uint64(binary.LittleEndian.Uint32(buf[16:20])) << 32
+	default:
+		return nil, fmt.Errorf("unsupported file capability revision: %#x", magic&vfsCapRevisionMask)
+	}
+
+	caps.Permitted = capabilityNames(permitted)
+	caps.Inheritable = capabilityNames(inheritable)
+	caps.Effective = magic&vfsCapEffective != 0
+
+	return caps, nil
+}
+
+// writeFileCapabilities grants capabilities to an executable like `setcap <caps>+ep`.
+// The kernel clears them whenever the file is rewritten, so they must be set again
+// after every core update. An empty list removes them.
+func writeFileCapabilities(path string, capabilities []string) error {
+	bits, err := parseCapabilities(capabilities)
+	if err != nil {
+		return err
+	}
+
+	if len(bits) == 0 {
+		err = unix.Removexattr(path, vfsCapXattr)
+		if errors.Is(err, unix.ENODATA) {
+			err = nil
+		}
+	} else {
+		var mask uint64
+		for _, bit := range bits {
+			mask |= 1 << bit
+		}
+
+		buf := make([]byte, 20)
+		binary.LittleEndian.PutUint32(buf[0:4], vfsCapRevision2|vfsCapEffective)
+		binary.LittleEndian.PutUint32(buf[4:8], uint32(mask))
+		binary.LittleEndian.PutUint32(buf[12:16], uint32(mask>>32))
+
+		err = unix.Setxattr(path, vfsCapXattr, buf, 0)
+	}
+
+	switch {
+	case errors.Is(err, unix.EPERM):
+		return errors.New("setting file capabilities requires root or CAP_SETFCAP")
+	case errors.Is(err, unix.ENOTSUP):
+		return errors.New("the filesystem does not support file capabilities")
+	}
+
+	return err
+}
@@ -0,0 +1,23 @@
+//go:build !linux
+
+package bridge
+
+import (
+	"errors"
+	"os/exec"
+)
+
+func applyCredential(cmd *exec.Cmd, options CredentialOptions) error {
+	if options.User != "" || options.Group != "" || len(options.Capabilities) > 0 {
+		return errors.New("running as another user or with capabilities is only supported on linux")
+	}
+	return nil
+}
+
+func readFileCapabilities(path string) (*fileCapabilities, error) {
+	return nil, errors.New("file capabilities are only supported on linux")
+}
+
+func writeFileCapabilities(path string, capabilities []string) error {
+	return errors.New("file capabilities are only supported on linux")
+}
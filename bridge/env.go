@@ -0,0 +1,246 @@
+package bridge
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	sysruntime "runtime"
+	"strings"
+)
+
+// Inherited variables a process can hardly run without, kept even in a clean environment.
+var essentialEnv = []string{"PATH", "HOME", "USER", "LANG", "TMPDIR", "TEMP", "TMP", "SYSTEMROOT", "WINDIR", "COMSPEC"}
+
+// commandEnv is an ordered environment where later assignments replace earlier ones.
+type commandEnv struct {
+	keys   []string
+	values map[string]string
+}
+
+func newCommandEnv() *commandEnv {
+	return &commandEnv{values: map[string]string{}}
+}
+
+// envKey folds names on Windows, where the environment is case-insensitive.
+func envKey(key string) string {
+	if sysruntime.GOOS == "windows" {
+		return strings.ToUpper(key)
+	}
+	return key
+}
+
+func (e *commandEnv) set(key string, value string) {
+	k := envKey(key)
+	if _, ok := e.values[k]; !ok {
+		e.keys = append(e.keys, key)
+	}
+	e.values[k] = value
+}
+
+func (e *commandEnv) lookup(key string) (string, bool) {
+	value, ok := e.values[envKey(key)]
+	return value, ok
+}
+
+func (e *commandEnv) remove(patterns []string) {
+	keys := e.keys[:0]
+	for _, key := range e.keys {
+		if matchEnvKey(patterns, key) {
+			delete(e.values, envKey(key))
+			continue
+		}
+		keys = append(keys, key)
+	}
+	e.keys = keys
+}
+
+func (e *commandEnv) list() []string {
+	list := make([]string, 0, len(e.keys))
+	for _, key := range e.keys {
+		list = append(list, key+"="+e.values[envKey(key)])
+	}
+	return list
+}
+
+// expand replaces ${VAR} with its value from the environment, falling back to the
+// built-in ${BasePath} / ${OS} / ${ARCH}. Unknown variables are left as they are.
+func (e *commandEnv) expand(s string) string {
+	if !strings.Contains(s, "${") {
+		return s
+	}
+
+	var b strings.Builder
+	for {
+		start := strings.Index(s, "${")
+		if start == -1 {
+			break
+		}
+		end := strings.IndexByte(s[start:], '}')
+		if end == -1 {
+			break
+		}
+
+		b.WriteString(s[:start])
+		name := s[start+2 : start+end]
+		if value, ok := e.lookup(name); ok {
+			b.WriteString(value)
+		} else {
+			switch name {
+			case "BasePath":
+				b.WriteString(Env.BasePath)
+			case "OS":
+				b.WriteString(Env.OS)
+			case "ARCH":
+				b.WriteString(Env.ARCH)
+			default:
+				b.WriteString(s[start : start+end+1])
+			}
+		}
+		s = s[start+end+1:]
+	}
+	b.WriteString(s)
+
+	return b.String()
+}
+
+func (e *commandEnv) expandIf(enabled bool, s string) string {
+	if !enabled {
+		return s
+	}
+	return e.expand(s)
+}
+
+func matchEnvKey(patterns []string, key string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(envKey(pattern), envKey(key)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// buildCommandEnv composes the child environment: the inherited variables (or only the
+// allowed ones in clean mode) minus UnsetEnv, then the env file, then Env. With ExpandEnv,
+// values may refer to variables defined before them.
+func buildCommandEnv(options ExecOptions) (*commandEnv, error) {
+	env := newCommandEnv()
+
+	for _, kv := range os.Environ() {
+		key, value, ok := strings.Cut(kv, "=")
+		// Windows keeps per-drive working directories in variables like "=C:".
+		if !ok || key == "" {
+			continue
+		}
+		if options.CleanEnv && !matchEnvKey(essentialEnv, key) && !matchEnvKey(options.EnvAllowlist, key) {
+			continue
+		}
+		env.set(key, value)
+	}
+
+	env.remove(options.UnsetEnv)
+
+	if options.EnvFile != "" {
+		envFile, err := sandboxPath(env.expandIf(options.ExpandEnv, options.EnvFile), sandboxRead)
+		if err != nil {
+			return nil, err
+		}
+		if err := loadEnvFile(env, envFile); err != nil {
+			return nil, err
+		}
+	}
+
+	// Env is a map without an order, so its values only see what was defined before it.
+	values := make(map[string]string, len(options.Env))
+	for key, value := range options.Env {
+		values[key] = env.expandIf(options.ExpandEnv, value)
+	}
+	for key, value := range values {
+		env.set(key, value)
+	}
+
+	return env, nil
+}
+
+func loadEnvFile(env *commandEnv, filePath string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return fmt.Errorf("%s:%d: invalid line", filePath, lineNo)
+		}
+
+		value, expand, err := parseEnvValue(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", filePath, lineNo, err)
+		}
+		if expand {
+			value = env.expand(value)
+		}
+
+		env.set(key, value)
+	}
+
+	return scanner.Err()
+}
+
+// parseEnvValue handles the quoting rules common to .env files: single quotes are literal,
+// double quotes understand escapes, unquoted values end at a comment. It reports whether
+// the value is subject to ${VAR} expansion.
+func parseEnvValue(raw string) (string, bool, error) {
+	switch {
+	case strings.HasPrefix(raw, "'"):
+		end := strings.IndexByte(raw[1:], '\'')
+		if end == -1 {
+			return "", false, errors.New("unterminated quote")
+		}
+		return raw[1 : end+1], false, nil
+
+	case strings.HasPrefix(raw, `"`):
+		var b strings.Builder
+		for i := 1; i < len(raw); i++ {
+			c := raw[i]
+			switch {
+			case c == '"':
+				return b.String(), true, nil
+			case c == '\\' && i+1 < len(raw):
+				i++
+				switch raw[i] {
+				case 'n':
+					b.WriteByte('\n')
+				case 't':
+					b.WriteByte('\t')
+				case 'r':
+					b.WriteByte('\r')
+				default:
+					b.WriteByte(raw[i])
+				}
+			default:
+				b.WriteByte(c)
+			}
+		}
+		return "", false, errors.New("unterminated quote")
+
+	default:
+		if i := strings.Index(raw, " #"); i != -1 {
+			raw = strings.TrimSpace(raw[:i])
+		}
+		return raw, true, nil
+	}
+}
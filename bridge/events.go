@@ -0,0 +1,227 @@
+package bridge
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+var (
+	eventStatsMap sync.Map
+	// eventStatsMu orders batchers opening and closing against stats being dropped.
+	eventStatsMu sync.Mutex
+)
+
+// eventStats outlive their batchers until they were read once or reset, so the totals
+// of a finished process are not lost.
+type eventStats struct {
+	Items    atomic.Uint64
+	Batches  atomic.Uint64
+	Dropped  atomic.Uint64
+	Buffered atomic.Int64
+	open     int // batchers using the stats, guarded by eventStatsMu
+}
+
+// eventBatcher groups high-volume events into arrays emitted at most once per interval.
+// When the frontend cannot keep up, the oldest items are dropped and replaced by a marker.
+// With a zero interval every item is emitted on its own, which keeps the statistics
+// consistent for callers that did not opt into batching.
+type eventBatcher struct {
+	app     *App
+	event   string
+	options BatchOptions
+	marker  func(dropped uint64) any
+	stats   *eventStats
+
+	mu      sync.Mutex
+	buffer  []any
+	head    int
+	count   int
+	dropped uint64
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (a *App) GetEventStats() FlagResult {
+	log.Printf("GetEventStats")
+
+	result := map[string]map[string]any{}
+
+	eventStatsMu.Lock()
+	eventStatsMap.Range(func(key, value any) bool {
+		stats := value.(*eventStats)
+		result[key.(string)] = map[string]any{
+			"items":    stats.Items.Load(),
+			"batches":  stats.Batches.Load(),
+			"dropped":  stats.Dropped.Load(),
+			"buffered": stats.Buffered.Load(),
+			"closed":   stats.open == 0,
+		}
+		if stats.open == 0 {
+			eventStatsMap.Delete(key)
+		}
+		return true
+	})
+	eventStatsMu.Unlock()
+
+	b, err := json.Marshal(result)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	return FlagResult{true, string(b)}
+}
+
+// ResetEventStats zeroes the statistics of event, or of every event when it is empty.
+func (a *App) ResetEventStats(event string) FlagResult {
+	log.Printf("ResetEventStats: %s", event)
+
+	eventStatsMu.Lock()
+	defer eventStatsMu.Unlock()
+
+	eventStatsMap.Range(func(key, value any) bool {
+		if event != "" && key != event {
+			return true
+		}
+		stats := value.(*eventStats)
+		if stats.open == 0 {
+			eventStatsMap.Delete(key)
+			return true
+		}
+		stats.Items.Store(0)
+		stats.Batches.Store(0)
+		stats.Dropped.Store(0)
+		return true
+	})
+
+	return FlagResult{true, "Success"}
+}
+
+func newEventBatcher(a *App, event string, options BatchOptions, marker func(dropped uint64) any) *eventBatcher {
+	if options.MaxSize <= 0 {
+		options.MaxSize = 100
+	}
+	if options.BufferSize < options.MaxSize {
+		options.BufferSize = max(options.MaxSize, 1000)
+	}
+
+	eventStatsMu.Lock()
+	value, _ := eventStatsMap.LoadOrStore(event, &eventStats{})
+	stats := value.(*eventStats)
+	stats.open++
+	eventStatsMu.Unlock()
+
+	b := &eventBatcher{
+		app:     a,
+		event:   event,
+		options: options,
+		marker:  marker,
+		stats:   stats,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	if options.Interval <= 0 {
+		close(b.done)
+		return b
+	}
+
+	b.buffer = make([]any, options.BufferSize)
+	go b.run()
+
+	return b
+}
+
+func (b *eventBatcher) push(item any) {
+	b.stats.Items.Add(1)
+
+	if b.options.Interval <= 0 {
+		b.stats.Batches.Add(1)
+		runtime.EventsEmit(b.app.Ctx, b.event, item)
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.count == len(b.buffer) {
+		b.head = (b.head + 1) % len(b.buffer)
+		b.count--
+		b.dropped++
+		b.stats.Dropped.Add(1)
+	}
+
+	b.buffer[(b.head+b.count)%len(b.buffer)] = item
+	b.count++
+	b.stats.Buffered.Store(int64(b.count))
+}
+
+// close flushes everything still buffered and stops the batcher.
+func (b *eventBatcher) close() {
+	b.closeOnce.Do(func() {
+		close(b.stop)
+		<-b.done
+
+		eventStatsMu.Lock()
+		b.stats.open--
+		eventStatsMu.Unlock()
+	})
+}
+
+func (b *eventBatcher) run() {
+	defer close(b.done)
+
+	ticker := time.NewTicker(time.Duration(b.options.Interval) * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			b.flush()
+		case <-b.stop:
+			for b.flush() {
+			}
+			return
+		}
+	}
+}
+
+// flush emits at most MaxSize buffered items and reports whether anything was emitted.
+func (b *eventBatcher) flush() bool {
+	b.mu.Lock()
+
+	if b.count == 0 && b.dropped == 0 {
+		b.mu.Unlock()
+		return false
+	}
+
+	batch := make([]any, 0, min(b.count, b.options.MaxSize)+1)
+	if b.dropped > 0 && b.marker != nil {
+		batch = append(batch, b.marker(b.dropped))
+	}
+	b.dropped = 0
+
+	for b.count > 0 && len(batch) < b.options.MaxSize {
+		batch = append(batch, b.buffer[b.head])
+		b.buffer[b.head] = nil
+		b.head = (b.head + 1) % len(b.buffer)
+		b.count--
+	}
+	b.stats.Buffered.Store(int64(b.count))
+
+	b.mu.Unlock()
+
+	if len(batch) == 0 {
+		return false
+	}
+
+	b.stats.Batches.Add(1)
+	runtime.EventsEmit(b.app.Ctx, b.event, batch)
+	return true
+}
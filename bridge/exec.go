@@ -3,6 +3,7 @@ package bridge
 import (
 	"bufio"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -10,10 +11,13 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	sysruntime "runtime"
 	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/shirou/gopsutil/v3/process"
@@ -23,22 +27,9 @@ import (
 func (a *App) Exec(path string, args []string, options ExecOptions) FlagResult {
 	log.Printf("Exec: %s %s %v", path, args, options)
 
-	exePath := resolvePath(path)
-
-	if _, err := os.Stat(exePath); os.IsNotExist(err) {
-		exePath = path
-	}
-
-	cmd := exec.Command(exePath, args...)
-	SetCmdWindowHidden(cmd)
-
-	if options.WorkingDirectory != "" {
-		cmd.Dir = resolvePath(options.WorkingDirectory)
-	}
-	cmd.Env = os.Environ()
-
-	for key, value := range options.Env {
-		cmd.Env = append(cmd.Env, key+"="+value)
+	cmd, err := newExecCommand(path, args, options)
+	if err != nil {
+		return FlagResult{false, err.Error()}
 	}
 
 	out, err := cmd.CombinedOutput()
@@ -58,103 +49,346 @@ func (a *App) Exec(path string, args []string, options ExecOptions) FlagResult {
 func (a *App) ExecBackground(path string, args []string, outEvent string, endEvent string, options ExecOptions) FlagResult {
 	log.Printf("ExecBackground: %s %s %s %s %v", path, args, outEvent, endEvent, options)
 
+	// Without a supervisor nothing would bring the process back.
+	if options.LivenessProbe.Restart {
+		return FlagResult{false, "LivenessProbe.Restart requires a supervised process, see StartSupervised"}
+	}
+
+	proc, err := startBackgroundProcess(a, path, args, outEvent, options)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	if err := proc.waitReady(); err != nil {
+		return FlagResult{false, err.Error()}
+	}
+	proc.watchLiveness(nil)
+
+	go func() {
+		err := proc.wait()
+
+		if endEvent != "" {
+			if err != nil {
+				runtime.EventsEmit(a.Ctx, endEvent, err.Error())
+				return
+			}
+			runtime.EventsEmit(a.Ctx, endEvent)
+		}
+	}()
+
+	return FlagResult{true, strconv.Itoa(proc.cmd.Process.Pid)}
+}
+
+const recentLineCount = 20
+
+type backgroundProcess struct {
+	app        *App
+	cmd        *exec.Cmd
+	pidPath    string
+	outEvent   string
+	options    ExecOptions
+	done       chan struct{}
+	outputDone chan struct{}
+	err        error
+	unhealthy  atomic.Bool
+
+	mu            sync.Mutex
+	recentLines   []string
+	outputStopped bool
+	ready         bool
+	logPattern    *regexp.Regexp
+	logReady      chan struct{}
+	logParser     *logParser
+	batcher       *eventBatcher
+	cgroup        *cgroupHandle
+}
+
+// validateExecOptions checks the options that are otherwise only looked at while the
+// process is started.
+func validateExecOptions(options ExecOptions) error {
+	if err := validateProbes(options); err != nil {
+		return err
+	}
+	if _, err := newLogParser(options.LogParser, options.LogLevel); err != nil {
+		return err
+	}
+	return validateResourceLimits(options.Limits)
+}
+
+func validateProbes(options ExecOptions) error {
+	if err := validateProbe(options.ReadinessProbe, false); err != nil {
+		return fmt.Errorf("readiness probe: %w", err)
+	}
+	if err := validateProbe(options.LivenessProbe, true); err != nil {
+		return fmt.Errorf("liveness probe: %w", err)
+	}
+	return nil
+}
+
+func newExecCommand(path string, args []string, options ExecOptions) (*exec.Cmd, error) {
+	env, err := buildCommandEnv(options)
+	if err != nil {
+		return nil, err
+	}
+
+	policy, path, err := sandboxPolicyFor(env.expandIf(options.ExpandEnv, path))
+	if err != nil {
+		return nil, err
+	}
 	exePath := resolvePath(path)
-	pidPath := ""
-	logPath := ""
 
 	if _, err := os.Stat(exePath); os.IsNotExist(err) {
+		// Not a file, the command is looked up in PATH.
+		if !policy.allowsCommand(path) {
+			return nil, &SandboxError{path, "command not allowed for " + policy.name}
+		}
 		exePath = path
+	} else if err := policy.check(path, exePath, sandboxRead); err != nil {
+		return nil, err
 	}
 
-	if options.PidFile != "" {
-		pidPath = resolvePath(options.PidFile)
+	expandedArgs := make([]string, len(args))
+	for i, arg := range args {
+		expandedArgs[i] = env.expandIf(options.ExpandEnv, arg)
 	}
 
-	done := make(chan struct{})
-	outputDone := make(chan struct{})
-	cmd := exec.Command(exePath, args...)
+	cmd := exec.Command(exePath, expandedArgs...)
 	SetCmdWindowHidden(cmd)
 
 	if options.WorkingDirectory != "" {
-		cmd.Dir = resolvePath(options.WorkingDirectory)
+		if cmd.Dir, err = sandboxPath(env.expandIf(options.ExpandEnv, options.WorkingDirectory), sandboxRead); err != nil {
+			return nil, err
+		}
+	}
+	cmd.Env = env.list()
+
+	if err := applyCredential(cmd, options.Credential); err != nil {
+		return nil, err
+	}
+
+	return cmd, nil
+}
+
+func startBackgroundProcess(a *App, path string, args []string, outEvent string, options ExecOptions) (*backgroundProcess, error) {
+	pidPath := ""
+	logPath := ""
+
+	if options.PidFile != "" {
+		var err error
+		if pidPath, err = sandboxPath(options.PidFile, sandboxWrite); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := validateProbes(options); err != nil {
+		return nil, err
+	}
+
+	logParser, err := newLogParser(options.LogParser, options.LogLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	// Output is also needed to evaluate log probes and to explain readiness failures.
+	captureOutput := outEvent != "" || options.ReadinessProbe.Type != ""
+
+	cmd, err := newExecCommand(path, args, options)
+	if err != nil {
+		return nil, err
 	}
-	cmd.Env = os.Environ()
 
-	for key, value := range options.Env {
-		cmd.Env = append(cmd.Env, key+"="+value)
+	cgroup, err := prepareResourceLimits(cmd, options.Limits)
+	if err != nil {
+		return nil, err
 	}
 
 	var stdout io.ReadCloser
-	var err error
 	var logFile *os.File
+	var logWriter *rotatingLogFile
 
 	switch {
+	case options.LogFile != "" && logRotationEnabled(options.LogRotate):
+		if logPath, err = sandboxPath(options.LogFile, sandboxWrite); err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(logPath), os.ModePerm); err != nil {
+			return nil, err
+		}
+
+		// The child writes through us so the file can be rotated underneath it.
+		logWriter, err = openRotatingLogFile(logPath, options.LogRotate)
+		if err != nil {
+			return nil, err
+		}
+
+		cmd.Stdout = logWriter
+		cmd.Stderr = logWriter
+
 	case options.LogFile != "":
-		logPath = resolvePath(options.LogFile)
+		if logPath, err = sandboxPath(options.LogFile, sandboxWrite); err != nil {
+			return nil, err
+		}
 		if err := os.MkdirAll(filepath.Dir(logPath), os.ModePerm); err != nil {
-			return FlagResult{false, err.Error()}
+			return nil, err
+		}
+
+		if options.LogRotate.KeepPrevious {
+			if err := rotateLogBackups(logPath, options.LogRotate); err != nil {
+				return nil, err
+			}
 		}
 
 		logFile, err = os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
 		if err != nil {
-			return FlagResult{false, err.Error()}
+			return nil, err
 		}
 		defer logFile.Close()
 
 		cmd.Stdout = logFile
 		cmd.Stderr = logFile
 
-	case outEvent != "":
+	case captureOutput:
 		stdout, err = cmd.StdoutPipe()
 		if err != nil {
-			return FlagResult{false, err.Error()}
+			return nil, err
 		}
 		cmd.Stderr = cmd.Stdout
 	}
 
 	if err := cmd.Start(); err != nil {
-		return FlagResult{false, err.Error()}
+		if logWriter != nil {
+			_ = logWriter.Close()
+		}
+		releaseResourceLimits(0, cgroup)
+		return nil, err
 	}
 
-	pid := strconv.Itoa(cmd.Process.Pid)
+	applyResourceLimits(cmd.Process.Pid, cgroup)
 
 	if pidPath != "" {
-		if err := os.WriteFile(pidPath, []byte(pid), os.ModePerm); err != nil {
+		if err := os.WriteFile(pidPath, []byte(strconv.Itoa(cmd.Process.Pid)), os.ModePerm); err != nil {
 			_ = SendExitSignal(cmd.Process)
 			_ = waitForProcessExitWithTimeout(cmd.Process, 10)
 			_ = cmd.Wait()
-			return FlagResult{false, err.Error()}
+			if logWriter != nil {
+				_ = logWriter.Close()
+			}
+			releaseResourceLimits(cmd.Process.Pid, cgroup)
+			return nil, err
 		}
 	}
 
+	proc := &backgroundProcess{
+		app:        a,
+		cmd:        cmd,
+		pidPath:    pidPath,
+		outEvent:   outEvent,
+		options:    options,
+		done:       make(chan struct{}),
+		outputDone: make(chan struct{}),
+		ready:      options.ReadinessProbe.Type == "",
+		logParser:  logParser,
+		cgroup:     cgroup,
+	}
+
 	if outEvent != "" {
+		proc.batcher = newEventBatcher(a, outEvent, options.Batch, func(dropped uint64) any {
+			text := fmt.Sprintf("[%d lines dropped]", dropped)
+			if logParser != nil {
+				return LogEntry{Level: "warn", Message: text}
+			}
+			return text
+		})
+	}
+
+	if options.ReadinessProbe.Type == ProbeLog {
+		proc.logPattern = regexp.MustCompile(options.ReadinessProbe.Pattern)
+		proc.logReady = make(chan struct{})
+	}
+
+	go func() {
+		proc.err = cmd.Wait()
+		releaseResourceLimits(cmd.Process.Pid, cgroup)
+		if logWriter != nil {
+			if err := logWriter.Close(); err != nil {
+				log.Printf("Failed to close log file %s: %v", logPath, err)
+			}
+		}
+		close(proc.done)
+	}()
+
+	if captureOutput {
 		if logPath != "" {
-			go tailAndEmitLogFile(a, logPath, outEvent, options, done, outputDone)
+			go tailAndEmitLogFile(proc, logPath, proc.done, proc.outputDone)
 		} else {
-			go scanAndEmitOutput(a, stdout, outEvent, options, outputDone)
+			go scanAndEmitOutput(proc, stdout, proc.outputDone)
 		}
 	} else {
-		close(outputDone)
+		close(proc.outputDone)
 	}
 
-	go func() {
-		err := cmd.Wait()
-		close(done)
-		<-outputDone
+	return proc, nil
+}
+
+// wait blocks until the process has exited and all of its output has been emitted.
+func (p *backgroundProcess) wait() error {
+	<-p.done
+	<-p.outputDone
+
+	if p.batcher != nil {
+		p.batcher.close()
+	}
 
-		if pidPath != "" {
-			_ = os.Remove(pidPath)
+	if p.pidPath != "" {
+		_ = os.Remove(p.pidPath)
+	}
+
+	return p.err
+}
+
+// handleLine records a line of output, feeds the log probe and forwards the line
+// to the frontend. It reports whether the caller still needs further output.
+func (p *backgroundProcess) handleLine(text string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.logParser != nil {
+		text = stripANSI(text)
+	}
+
+	p.recentLines = append(p.recentLines, text)
+	if len(p.recentLines) > recentLineCount {
+		p.recentLines = p.recentLines[len(p.recentLines)-recentLineCount:]
+	}
+
+	if p.logPattern != nil && p.logPattern.MatchString(text) {
+		p.logPattern = nil
+		close(p.logReady)
+	}
+
+	if p.outEvent != "" && !p.outputStopped {
+		if p.logParser == nil {
+			p.batcher.push(text)
+		} else if entry, ok := p.logParser.parse(text); ok {
+			p.batcher.push(entry)
 		}
-		if endEvent != "" {
-			if err != nil {
-				runtime.EventsEmit(a.Ctx, endEvent, err.Error())
-				return
-			}
-			runtime.EventsEmit(a.Ctx, endEvent)
+
+		if p.options.StopOutputKeyword != "" && strings.Contains(text, p.options.StopOutputKeyword) {
+			p.outputStopped = true
 		}
-	}()
+	}
+
+	return (p.outEvent != "" && !p.outputStopped) || !p.ready
+}
+
+func (p *backgroundProcess) recentOutput() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 
-	return FlagResult{true, pid}
+	if len(p.recentLines) == 0 {
+		return ""
+	}
+	return "\n" + strings.Join(p.recentLines, "\n")
 }
 
 func (a *App) ProcessInfo(pid int32) FlagResult {
@@ -206,6 +440,10 @@ func (a *App) ProcessMemory(pid int32) FlagResult {
 		return FlagResult{false, err.Error()}
 	}
 
+	if total, ok := managedCgroupMemory(pid); ok {
+		return FlagResult{true, strconv.FormatUint(total, 10)}
+	}
+
 	memInfo, err := proc.MemoryInfo()
 	if err == nil && memInfo != nil {
 		return FlagResult{true, strconv.FormatUint(memInfo.RSS, 10)}
@@ -222,6 +460,22 @@ func (a *App) ProcessMemory(pid int32) FlagResult {
 	return FlagResult{false, err.Error()}
 }
 
+func (a *App) ProcessCgroupStats(pid int32) FlagResult {
+	log.Printf("ProcessCgroupStats: %d", pid)
+
+	stats, err := processCgroupStats(pid)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	b, err := json.Marshal(stats)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	return FlagResult{true, string(b)}
+}
+
 func (a *App) KillProcess(pid int, timeout int) FlagResult {
 	log.Printf("KillProcess: %d %d", pid, timeout)
 
@@ -294,29 +548,21 @@ func getDarwinProcessRSS(pid int32) (uint64, error) {
 	return rss * 1024, nil
 }
 
-func scanAndEmitOutput(a *App, reader io.Reader, outEvent string, options ExecOptions, outputDone chan<- struct{}) {
+func scanAndEmitOutput(proc *backgroundProcess, reader io.Reader, outputDone chan<- struct{}) {
 	defer close(outputDone)
 
 	scanner := bufio.NewScanner(reader)
 	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
-	stopOutput := false
 
+	// Keep draining the pipe even when nobody listens, otherwise the child blocks on a full pipe.
 	for scanner.Scan() {
-		text := DecodeCommandOutput(scanner.Bytes())
-
-		if !stopOutput {
-			runtime.EventsEmit(a.Ctx, outEvent, text)
-
-			if options.StopOutputKeyword != "" && strings.Contains(text, options.StopOutputKeyword) {
-				stopOutput = true
-			}
-		}
+		proc.handleLine(DecodeCommandOutput(scanner.Bytes()))
 	}
 
 	_ = scanner.Err()
 }
 
-func tailAndEmitLogFile(a *App, path string, outEvent string, options ExecOptions, done <-chan struct{}, outputDone chan<- struct{}) {
+func tailAndEmitLogFile(proc *backgroundProcess, path string, done <-chan struct{}, outputDone chan<- struct{}) {
 	defer close(outputDone)
 
 	offset := int64(0)
@@ -332,28 +578,10 @@ func tailAndEmitLogFile(a *App, path string, outEvent string, options ExecOption
 			return false
 		}
 
-		runtime.EventsEmit(a.Ctx, outEvent, text)
-		return options.StopOutputKeyword != "" && strings.Contains(text, options.StopOutputKeyword)
+		return !proc.handleLine(text)
 	}
 
-	readNewContent := func(flush bool) bool {
-		data, nextOffset, err := readFileRange(path, offset)
-		if err != nil {
-			if !errors.Is(err, os.ErrNotExist) {
-				log.Printf("Failed to read log file %s: %v", path, err)
-			}
-			return false
-		}
-
-		if len(data) == 0 {
-			if flush && pending != "" {
-				return emitLine(pending)
-			}
-			offset = nextOffset
-			return false
-		}
-
-		offset = nextOffset
+	consume := func(data []byte, flush bool) bool {
 		chunk := pending + string(data)
 		lines := strings.Split(chunk, "\n")
 		pending = lines[len(lines)-1]
@@ -374,6 +602,44 @@ func tailAndEmitLogFile(a *App, path string, outEvent string, options ExecOption
 		return false
 	}
 
+	var current os.FileInfo
+
+	readNewContent := func(flush bool) bool {
+		if info, err := os.Stat(path); err == nil {
+			if current != nil && !os.SameFile(current, info) {
+				// The log was rotated: drain what is left of the previous file before starting over.
+				if rotated, err := os.Stat(path + ".1"); err == nil && os.SameFile(current, rotated) {
+					if data, _, err := readFileRange(path+".1", offset); err == nil && consume(data, false) {
+						return true
+					}
+				}
+				offset = 0
+			} else if info.Size() < offset {
+				offset = 0
+			}
+			current = info
+		}
+
+		data, nextOffset, err := readFileRange(path, offset)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				log.Printf("Failed to read log file %s: %v", path, err)
+			}
+			return false
+		}
+
+		if len(data) == 0 {
+			if flush && pending != "" {
+				return emitLine(pending)
+			}
+			offset = nextOffset
+			return false
+		}
+
+		offset = nextOffset
+		return consume(data, flush)
+	}
+
 	for {
 		select {
 		case <-done:
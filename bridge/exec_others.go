@@ -14,6 +14,12 @@ func DecodeCommandOutput(data []byte) string {
 	return string(data)
 }
 
+// commandOutputTail returns the length of an incomplete character at the end of a
+// chunk of output, to be decoded together with the next chunk.
+func commandOutputTail(data []byte) int {
+	return utf8Tail(data)
+}
+
 func SetCmdWindowHidden(cmd *exec.Cmd) {
 }
 
@@ -21,6 +27,23 @@ func SendExitSignal(p *os.Process) error {
 	return p.Signal(syscall.SIGINT)
 }
 
+func SignalProcess(p *os.Process, name string) error {
+	signals := map[string]syscall.Signal{
+		"SIGHUP":  syscall.SIGHUP,
+		"SIGINT":  syscall.SIGINT,
+		"SIGQUIT": syscall.SIGQUIT,
+		"SIGTERM": syscall.SIGTERM,
+		"SIGKILL": syscall.SIGKILL,
+	}
+
+	signal, ok := signals[name]
+	if !ok {
+		return fmt.Errorf("unsupported signal: %s", name)
+	}
+
+	return p.Signal(signal)
+}
+
 func IsProcessAlive(p *os.Process) (bool, error) {
 	err := p.Signal(syscall.Signal(0))
 	if err == nil {
@@ -27,6 +27,7 @@ var (
 	procSetConsoleCtrlHandler    = modKernel32.NewProc("SetConsoleCtrlHandler")
 	procGenerateConsoleCtrlEvent = modKernel32.NewProc("GenerateConsoleCtrlEvent")
 	procGetOEMCP                 = modKernel32.NewProc("GetOEMCP")
+	procIsDBCSLeadByteEx         = modKernel32.NewProc("IsDBCSLeadByteEx")
 )
 
 func DecodeCommandOutput(data []byte) string {
@@ -59,6 +60,31 @@ func DecodeCommandOutput(data []byte) string {
 	return string(utf16.Decode(wide[:length]))
 }
 
+// commandOutputTail returns the length of an incomplete character at the end of a
+// chunk of output, either UTF-8 or a lead byte of a double-byte OEM code page like GBK.
+func commandOutputTail(data []byte) int {
+	if n := utf8Tail(data); n > 0 && utf8.Valid(data[:len(data)-n]) {
+		return n
+	}
+	if utf8.Valid(data) {
+		return 0
+	}
+
+	codePage, _, _ := procGetOEMCP.Call()
+	for i := 0; i < len(data); i++ {
+		if data[i] < utf8.RuneSelf {
+			continue
+		}
+		if lead, _, _ := procIsDBCSLeadByteEx.Call(codePage, uintptr(data[i])); lead != 0 {
+			if i == len(data)-1 {
+				return 1
+			}
+			i++
+		}
+	}
+	return 0
+}
+
 func SetCmdWindowHidden(cmd *exec.Cmd) {
 	cmd.SysProcAttr = &syscall.SysProcAttr{
 		CreationFlags: windows.CREATE_UNICODE_ENVIRONMENT | windows.CREATE_NEW_PROCESS_GROUP,
@@ -90,6 +116,19 @@ func SendExitSignal(p *os.Process) error {
 	return nil
 }
 
+// SignalProcess maps unix signal names onto what windows offers: a console
+// break for the polite ones and TerminateProcess for the rest.
+func SignalProcess(p *os.Process, name string) error {
+	switch name {
+	case "SIGINT", "SIGHUP", "SIGQUIT":
+		return SendExitSignal(p)
+	case "SIGTERM", "SIGKILL":
+		return p.Kill()
+	default:
+		return fmt.Errorf("unsupported signal: %s", name)
+	}
+}
+
 func IsProcessAlive(p *os.Process) (bool, error) {
 	h, err := windows.OpenProcess(windows.SYNCHRONIZE, false, uint32(p.Pid))
 	if err != nil {
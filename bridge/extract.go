@@ -0,0 +1,632 @@
+package bridge
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+const (
+	formatZip   = "zip"
+	formatTar   = "tar"
+	formatGzip  = "gzip"
+	formatXz    = "xz"
+	formatZstd  = "zstd"
+	formatBzip2 = "bzip2"
+)
+
+type extractResult struct {
+	Format    string        `json:"format"`
+	Extracted []string      `json:"extracted"`
+	Skipped   []extractSkip `json:"skipped"`
+	Bytes     int64         `json:"bytes"`
+}
+
+type extractSkip struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// extractor writes entries below dest. In strict mode dest is a staging directory
+// that replaces the contents of output only once every entry was extracted.
+type extractor struct {
+	output  string
+	dest    string
+	options ExtractOptions
+	result  *extractResult
+}
+
+func (a *App) ExtractArchive(path string, output string, options ExtractOptions) FlagResult {
+	log.Printf("ExtractArchive: %s -> %s %v", path, output, options)
+
+	fullPath, err := sandboxPath(path, sandboxRead)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+	outputPath, err := sandboxPath(output, sandboxWrite)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	file, err := os.Open(fullPath)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	return extractArchiveResult(file, stat.Size(), filepath.Base(fullPath), outputPath, options)
+}
+
+// ExtractArchiveBase64 extracts an archive that was fetched into memory.
+func (a *App) ExtractArchiveBase64(content string, output string, options ExtractOptions) FlagResult {
+	log.Printf("ExtractArchiveBase64: %d bytes -> %s %v", len(content), output, options)
+
+	outputPath, err := sandboxPath(output, sandboxWrite)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	data, err := base64.StdEncoding.DecodeString(content)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	return extractArchiveResult(bytes.NewReader(data), int64(len(data)), "", outputPath, options)
+}
+
+func extractArchiveResult(r io.ReaderAt, size int64, name string, outputPath string, options ExtractOptions) FlagResult {
+	result, err := extractArchive(r, size, name, outputPath, options)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	b, err := json.Marshal(result)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	return FlagResult{true, string(b)}
+}
+
+// extractArchive detects the format of r and extracts it.
+func extractArchive(r io.ReaderAt, size int64, name string, outputPath string, options ExtractOptions) (*extractResult, error) {
+	header := make([]byte, 512)
+	n, err := r.ReadAt(header, 0)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	format := detectArchiveFormat(header[:n])
+	if format == "" {
+		return nil, errors.New("unknown archive format")
+	}
+
+	result := &extractResult{Format: format, Extracted: []string{}, Skipped: []extractSkip{}}
+
+	if format == formatZip {
+		zr, err := zip.NewReader(r, size)
+		if err != nil {
+			return nil, err
+		}
+		return result, runExtractor(outputPath, options, result, func(e *extractor) error {
+			return e.extractZip(zr)
+		})
+	}
+
+	var stream io.Reader = io.NewSectionReader(r, 0, size)
+	if format != formatTar {
+		rc, err := decompressStream(format, stream)
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+
+		// A compressed stream is either a tarball or a single file.
+		buffered := bufio.NewReaderSize(rc, 64*1024)
+		if peek, _ := buffered.Peek(512); detectArchiveFormat(peek) != formatTar {
+			return result, extractSingleFile(buffered, name, outputPath, result)
+		}
+		result.Format = "tar+" + format
+		stream = buffered
+	}
+
+	return result, runExtractor(outputPath, options, result, func(e *extractor) error {
+		return e.extractTar(tar.NewReader(stream))
+	})
+}
+
+func runExtractor(outputPath string, options ExtractOptions, result *extractResult, extract func(e *extractor) error) error {
+	e := &extractor{output: outputPath, dest: outputPath, options: options, result: result}
+
+	if !options.Strict {
+		if err := os.MkdirAll(outputPath, os.ModePerm); err != nil {
+			return err
+		}
+		return extract(e)
+	}
+
+	// Stage inside the output, the only directory the caller was allowed to write to.
+	_, statErr := os.Stat(outputPath)
+	if err := os.MkdirAll(outputPath, os.ModePerm); err != nil {
+		return err
+	}
+	staging, err := os.MkdirTemp(outputPath, ".extract-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+
+	e.dest = filepath.ToSlash(staging)
+	if err := extract(e); err != nil {
+		os.RemoveAll(staging)
+		if os.IsNotExist(statErr) {
+			os.Remove(outputPath)
+		}
+		return err
+	}
+
+	return mergeExtracted(e.dest, outputPath)
+}
+
+// mergeExtracted moves a finished staging directory into output, replacing existing files.
+func mergeExtracted(staging string, outputPath string) error {
+	return filepath.WalkDir(staging, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(staging, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(outputPath, rel)
+
+		if d.IsDir() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if stat, err := os.Lstat(target); err == nil && !stat.IsDir() {
+				if err := os.Remove(target); err != nil {
+					return err
+				}
+			}
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		}
+
+		if stat, err := os.Lstat(target); err == nil && stat.IsDir() {
+			if err := os.RemoveAll(target); err != nil {
+				return err
+			}
+		}
+		return os.Rename(p, target)
+	})
+}
+
+func extractSingleFile(r io.Reader, name string, outputPath string, result *extractResult) error {
+	target := outputPath
+	if stat, err := os.Stat(outputPath); err == nil && stat.IsDir() {
+		if name == "" {
+			return errors.New("output must be a file path for a compressed file without a name")
+		}
+		target = filepath.ToSlash(filepath.Join(outputPath, strings.TrimSuffix(name, filepath.Ext(name))))
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+		return err
+	}
+
+	// Decompress next to the target so a corrupt stream never replaces a good file.
+	tmp, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	// CreateTemp makes the file private, keep the mode of the file being replaced.
+	perm := os.FileMode(0644)
+	if stat, statErr := os.Stat(target); statErr == nil {
+		perm = stat.Mode().Perm()
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), perm)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), target)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	result.Extracted = append(result.Extracted, target)
+	result.Bytes += n
+
+	return nil
+}
+
+func detectArchiveFormat(header []byte) string {
+	switch {
+	case bytes.HasPrefix(header, []byte("PK\x03\x04")), bytes.HasPrefix(header, []byte("PK\x05\x06")):
+		return formatZip
+	case bytes.HasPrefix(header, []byte{0x1f, 0x8b}):
+		return formatGzip
+	case bytes.HasPrefix(header, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}):
+		return formatXz
+	case bytes.HasPrefix(header, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return formatZstd
+	case bytes.HasPrefix(header, []byte("BZh")):
+		return formatBzip2
+	case len(header) >= 262 && string(header[257:262]) == "ustar":
+		return formatTar
+	}
+	return ""
+}
+
+func decompressStream(format string, r io.Reader) (io.ReadCloser, error) {
+	switch format {
+	case formatGzip:
+		return gzip.NewReader(r)
+	case formatXz:
+		xr, err := xz.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(xr), nil
+	case formatZstd:
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return zr.IOReadCloser(), nil
+	case formatBzip2:
+		return io.NopCloser(bzip2.NewReader(r)), nil
+	}
+	return nil, fmt.Errorf("unsupported compression: %s", format)
+}
+
+// extractEntryName applies entry selection and StripComponents. It returns an empty
+// name for entries that are not wanted.
+func extractEntryName(name string, options ExtractOptions) string {
+	name = path.Clean(strings.ReplaceAll(name, `\`, "/"))
+	if name == "." {
+		return ""
+	}
+
+	// Leave names escaping the output alone so archiveEntryPath rejects them.
+	if name == ".." || strings.HasPrefix(name, "../") || path.IsAbs(name) {
+		return name
+	}
+
+	if len(options.Entries) > 0 && !matchExtractEntry(options.Entries, name) {
+		return ""
+	}
+
+	if options.StripComponents > 0 {
+		parts := strings.Split(name, "/")
+		if len(parts) <= options.StripComponents {
+			return ""
+		}
+		name = strings.Join(parts[options.StripComponents:], "/")
+	}
+
+	return name
+}
+
+// matchExtractEntry selects entries by glob, a directory name selects everything below it.
+func matchExtractEntry(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.TrimPrefix(pattern, "./")
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+		if dir := strings.TrimSuffix(pattern, "/"); strings.HasPrefix(name, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// skip records an entry that could not be extracted. In strict mode it aborts the extraction.
+func (e *extractor) skip(name string, reason string) error {
+	if e.options.Strict {
+		return fmt.Errorf("%s: %s", name, reason)
+	}
+	e.result.Skipped = append(e.result.Skipped, extractSkip{name, reason})
+	return nil
+}
+
+// target resolves an entry name to the path it is written to and the path it will end up at.
+func (e *extractor) target(name string) (string, string, error) {
+	dest, ok := archiveEntryPath(e.dest, name)
+	if !ok {
+		return "", "", errors.New("unsafe path")
+	}
+
+	// An earlier symlink entry must not redirect later entries out of the output.
+	parent, err := filepath.EvalSymlinks(filepath.Dir(dest))
+	if err == nil {
+		root, err := filepath.EvalSymlinks(e.dest)
+		if err != nil {
+			return "", "", err
+		}
+		if rel, err := filepath.Rel(root, parent); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(os.PathSeparator)) {
+			return "", "", errors.New("unsafe path")
+		}
+	}
+
+	final, _ := archiveEntryPath(e.output, name)
+	return dest, final, nil
+}
+
+// linkTarget validates a symlink target relative to the path of the link. Links that
+// earlier entries left are followed while walking the target, like the kernel will,
+// so d -> . cannot make d/../outside look like a path inside the output.
+func (e *extractor) linkTarget(dest string, link string) error {
+	link = strings.ReplaceAll(link, `\`, "/")
+	if link == "" || path.IsAbs(link) || filepath.IsAbs(link) {
+		return errors.New("unsafe link target")
+	}
+
+	root, err := filepath.EvalSymlinks(e.dest)
+	if err != nil {
+		return err
+	}
+
+	current := filepath.FromSlash(evalExistingSymlinks(filepath.Dir(dest)))
+	for _, part := range strings.Split(link, "/") {
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			current = filepath.Dir(current)
+		default:
+			current = filepath.Join(current, part)
+			if real, err := filepath.EvalSymlinks(current); err == nil {
+				current = real
+			}
+		}
+		if !pathWithin(root, current) {
+			return errors.New("unsafe link target")
+		}
+	}
+
+	return nil
+}
+
+func (e *extractor) extractZip(zr *zip.Reader) error {
+	for _, f := range zr.File {
+		name := extractEntryName(f.Name, e.options)
+		if name == "" {
+			continue
+		}
+
+		var err error
+		switch mode := f.Mode(); {
+		case mode.IsDir():
+			err = e.extractDir(name)
+		case mode&os.ModeSymlink != 0:
+			err = e.extractZipSymlink(name, f)
+		case mode.IsRegular():
+			err = e.extractZipFile(name, f)
+		default:
+			err = errors.New("unsupported entry type")
+		}
+
+		if err != nil {
+			if err := e.skip(f.Name, err.Error()); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (e *extractor) extractZipFile(name string, f *zip.File) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	return e.extractFile(name, rc, f.Mode())
+}
+
+func (e *extractor) extractZipSymlink(name string, f *zip.File) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	link, err := io.ReadAll(io.LimitReader(rc, 4096))
+	if err != nil {
+		return err
+	}
+
+	return e.extractSymlink(name, string(link))
+}
+
+func (e *extractor) extractTar(tr *tar.Reader) error {
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// Metadata of the archive as a whole, e.g. the commit id git archive records.
+		if header.Typeflag == tar.TypeXGlobalHeader {
+			continue
+		}
+
+		name := extractEntryName(header.Name, e.options)
+		if name == "" {
+			continue
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = e.extractDir(name)
+		case tar.TypeReg:
+			err = e.extractFile(name, tr, header.FileInfo().Mode())
+		case tar.TypeSymlink:
+			err = e.extractSymlink(name, header.Linkname)
+		case tar.TypeLink:
+			err = e.extractHardlink(name, header.Linkname)
+		case tar.TypeChar, tar.TypeBlock, tar.TypeFifo:
+			// Never extracted, which does not make the archive unusable.
+			e.result.Skipped = append(e.result.Skipped, extractSkip{header.Name, "device or fifo entry"})
+			continue
+		default:
+			err = errors.New("unsupported entry type")
+		}
+
+		if err != nil {
+			if err := e.skip(header.Name, err.Error()); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (e *extractor) extractDir(name string) error {
+	dest, _, err := e.target(name)
+	if err != nil {
+		return err
+	}
+	return os.MkdirAll(dest, os.ModePerm)
+}
+
+func (e *extractor) extractFile(name string, r io.Reader, mode os.FileMode) error {
+	dest, final, err := e.target(name)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+		return err
+	}
+
+	// Never write through a link an earlier entry left at this path.
+	if stat, err := os.Lstat(dest); err == nil && stat.Mode()&os.ModeSymlink != 0 {
+		if err := os.Remove(dest); err != nil {
+			return err
+		}
+	}
+
+	dstFile, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return err
+	}
+
+	n, err := io.Copy(dstFile, r)
+	if closeErr := dstFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	e.result.Extracted = append(e.result.Extracted, final)
+	e.result.Bytes += n
+
+	return nil
+}
+
+func (e *extractor) extractSymlink(name string, link string) error {
+	dest, final, err := e.target(name)
+	if err != nil {
+		return err
+	}
+	if err := e.linkTarget(dest, link); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+		return err
+	}
+	if err := os.Remove(dest); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := os.Symlink(filepath.FromSlash(link), dest); err != nil {
+		return err
+	}
+
+	e.result.Extracted = append(e.result.Extracted, final)
+
+	return nil
+}
+
+// extractHardlink links to an entry extracted earlier, falling back to a copy where
+// the filesystem has no hard links.
+func (e *extractor) extractHardlink(name string, linkname string) error {
+	dest, final, err := e.target(name)
+	if err != nil {
+		return err
+	}
+
+	linkName := extractEntryName(linkname, ExtractOptions{StripComponents: e.options.StripComponents})
+	if linkName == "" {
+		return errors.New("link target is not extracted")
+	}
+	source, _, err := e.target(linkName)
+	if err != nil {
+		return errors.New("unsafe link target")
+	}
+
+	stat, err := os.Lstat(source)
+	if err != nil {
+		return errors.New("link target is not extracted")
+	}
+	if !stat.Mode().IsRegular() {
+		return errors.New("link target is not a regular file")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+		return err
+	}
+	if err := os.Remove(dest); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	if err := os.Link(source, dest); err != nil {
+		src, err := os.Open(source)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		return e.extractFile(name, src, stat.Mode())
+	}
+
+	e.result.Extracted = append(e.result.Extracted, final)
+
+	return nil
+}
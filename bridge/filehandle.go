@@ -0,0 +1,268 @@
+package bridge
+
+import (
+	"encoding/base64"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultChunkSize = 1024 * 1024
+	maxChunkSize     = 16 * 1024 * 1024
+)
+
+var (
+	fileHandleMap     sync.Map
+	fileHandleCounter atomic.Uint64
+)
+
+type fileHandle struct {
+	id    string
+	path  string
+	file  *os.File
+	idle  time.Duration
+	timer *time.Timer
+
+	mu     sync.Mutex
+	closed bool
+}
+
+func (a *App) OpenFileHandle(path string, options FileHandleOptions) FlagResult {
+	log.Printf("OpenFileHandle [%s]: %s", options.Access, path)
+
+	flag := os.O_RDONLY
+	access := sandboxRead
+	switch options.Access {
+	case "", "read":
+	case "write":
+		flag, access = os.O_WRONLY|os.O_CREATE|os.O_TRUNC, sandboxWrite
+	case "append":
+		flag, access = os.O_WRONLY|os.O_CREATE|os.O_APPEND, sandboxWrite
+	case "readwrite":
+		flag, access = os.O_RDWR|os.O_CREATE, sandboxWrite
+	default:
+		return FlagResult{false, "Unsupported access: " + options.Access}
+	}
+
+	fullPath, err := sandboxPath(path, access)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	if flag&os.O_CREATE != 0 {
+		if err := os.MkdirAll(filepath.Dir(fullPath), os.ModePerm); err != nil {
+			return FlagResult{false, err.Error()}
+		}
+	}
+
+	file, err := os.OpenFile(fullPath, flag, 0644)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	idle := time.Duration(options.IdleTimeout) * time.Second
+	if idle <= 0 {
+		idle = 60 * time.Second
+	}
+
+	h := &fileHandle{
+		id:   "file-" + strconv.FormatUint(fileHandleCounter.Add(1), 10),
+		path: fullPath,
+		file: file,
+		idle: idle,
+	}
+	// Handles the frontend forgot about must not keep files open forever.
+	h.timer = time.AfterFunc(idle, func() {
+		log.Printf("FileHandle %s idle, closing %s", h.id, h.path)
+		h.close()
+	})
+
+	fileHandleMap.Store(h.id, h)
+
+	return FlagResult{true, h.id}
+}
+
+// ReadChunk reads up to size bytes from the current position, or the given
+// options.Range without moving it. An empty result means the end of the file.
+func (a *App) ReadChunk(id string, size int, options IOOptions) FlagResult {
+	log.Printf("ReadChunk [%s %s]: %s %d", options.Mode, options.Range, id, size)
+
+	h, err := loadFileHandle(id)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+	defer h.mu.Unlock()
+
+	if size <= 0 {
+		size = defaultChunkSize
+	}
+
+	var buf []byte
+
+	if options.Range != "" {
+		stat, err := h.file.Stat()
+		if err != nil {
+			return FlagResult{false, err.Error()}
+		}
+		start, end, err := parseByteRange(options.Range, stat.Size())
+		if err != nil {
+			return FlagResult{false, err.Error()}
+		}
+		length := end - start + 1
+		if length <= 0 {
+			return FlagResult{true, ""}
+		}
+		if length > maxChunkSize {
+			return FlagResult{false, "range exceeds the maximum chunk size"}
+		}
+
+		buf = make([]byte, length)
+		n, err := h.file.ReadAt(buf, start)
+		if err != nil && err != io.EOF {
+			return FlagResult{false, err.Error()}
+		}
+		buf = buf[:n]
+	} else {
+		buf = make([]byte, min(size, maxChunkSize))
+		n, err := io.ReadFull(h.file, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return FlagResult{false, err.Error()}
+		}
+		buf = buf[:n]
+	}
+
+	switch options.Mode {
+	case Text:
+		return FlagResult{true, string(buf)}
+	case Binary:
+		return FlagResult{true, base64.StdEncoding.EncodeToString(buf)}
+	default:
+		return FlagResult{false, "Unsupported IO mode: " + options.Mode}
+	}
+}
+
+// WriteChunk writes at the current position, or over options.Range, and returns the
+// number of bytes written.
+func (a *App) WriteChunk(id string, content string, options IOOptions) FlagResult {
+	log.Printf("WriteChunk [%s %s]: %s", options.Mode, options.Range, id)
+
+	var data []byte
+	var err error
+
+	switch options.Mode {
+	case Text:
+		data = []byte(content)
+	case Binary:
+		data, err = base64.StdEncoding.DecodeString(content)
+		if err != nil {
+			return FlagResult{false, err.Error()}
+		}
+	default:
+		return FlagResult{false, "Unsupported IO mode: " + options.Mode}
+	}
+
+	h, err := loadFileHandle(id)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+	defer h.mu.Unlock()
+
+	var n int
+
+	if options.Range != "" {
+		stat, err := h.file.Stat()
+		if err != nil {
+			return FlagResult{false, err.Error()}
+		}
+		start, end, err := parseByteRange(options.Range, stat.Size())
+		if err != nil {
+			return FlagResult{false, err.Error()}
+		}
+		if int64(len(data)) != end-start+1 {
+			return FlagResult{false, "data length does not match range length"}
+		}
+		n, err = h.file.WriteAt(data, start)
+		if err != nil {
+			return FlagResult{false, err.Error()}
+		}
+	} else {
+		n, err = h.file.Write(data)
+		if err != nil {
+			return FlagResult{false, err.Error()}
+		}
+	}
+
+	return FlagResult{true, strconv.Itoa(n)}
+}
+
+// Seek moves the position of a handle like io.Seeker and returns the new one.
+func (a *App) Seek(id string, offset int64, whence int) FlagResult {
+	log.Printf("Seek: %s %d %d", id, offset, whence)
+
+	h, err := loadFileHandle(id)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+	defer h.mu.Unlock()
+
+	pos, err := h.file.Seek(offset, whence)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	return FlagResult{true, strconv.FormatInt(pos, 10)}
+}
+
+func (a *App) CloseFileHandle(id string) FlagResult {
+	log.Printf("CloseFileHandle: %s", id)
+
+	value, ok := fileHandleMap.Load(id)
+	if !ok {
+		return FlagResult{false, "file handle not found"}
+	}
+
+	if err := value.(*fileHandle).close(); err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	return FlagResult{true, "Success"}
+}
+
+// loadFileHandle returns the handle locked, with its idle timer restarted.
+func loadFileHandle(id string) (*fileHandle, error) {
+	value, ok := fileHandleMap.Load(id)
+	if !ok {
+		return nil, errors.New("file handle not found")
+	}
+
+	h := value.(*fileHandle)
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return nil, errors.New("file handle not found")
+	}
+	h.timer.Reset(h.idle)
+
+	return h, nil
+}
+
+func (h *fileHandle) close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil
+	}
+	h.closed = true
+	h.timer.Stop()
+	fileHandleMap.Delete(h.id)
+
+	return h.file.Close()
+}
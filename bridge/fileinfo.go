@@ -0,0 +1,256 @@
+package bridge
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type fileStat struct {
+	Name       string `json:"name"`
+	Path       string `json:"path"`
+	Size       int64  `json:"size"`
+	IsDir      bool   `json:"isDir"`
+	Mode       string `json:"mode"`
+	Perm       uint32 `json:"perm"`
+	ModTime    int64  `json:"modTime"`
+	IsSymlink  bool   `json:"isSymlink"`
+	LinkTarget string `json:"linkTarget,omitempty"`
+	Owner      string `json:"owner"`
+	Group      string `json:"group"`
+	Uid        int    `json:"uid"` // -1 on Windows
+	Gid        int    `json:"gid"`
+}
+
+func (a *App) Stat(path string) FlagResult {
+	log.Printf("Stat: %s", path)
+
+	fullPath, err := sandboxPath(path, sandboxRead)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	info, err := os.Lstat(fullPath)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	stat := fileStat{
+		Name:      info.Name(),
+		Path:      fullPath,
+		Size:      info.Size(),
+		IsDir:     info.IsDir(),
+		Mode:      info.Mode().String(),
+		Perm:      uint32(info.Mode().Perm()),
+		ModTime:   info.ModTime().UnixMilli(),
+		IsSymlink: info.Mode()&os.ModeSymlink != 0,
+	}
+	if stat.IsSymlink {
+		stat.LinkTarget, _ = os.Readlink(fullPath)
+		if target, err := os.Stat(fullPath); err == nil {
+			stat.IsDir = target.IsDir()
+		}
+	}
+	stat.Owner, stat.Group, stat.Uid, stat.Gid = fileOwner(fullPath, info)
+
+	b, err := json.Marshal(stat)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	return FlagResult{true, string(b)}
+}
+
+// Chmod accepts octal modes like 755 and symbolic ones like +x or u+rw,go-w.
+// Windows only knows whether a file is writable.
+func (a *App) Chmod(path string, mode string) FlagResult {
+	log.Printf("Chmod: %s %s", path, mode)
+
+	fullPath, err := sandboxPath(path, sandboxWrite)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	perm, err := parseFileMode(mode, info.Mode().Perm())
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	if err := os.Chmod(fullPath, perm); err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	return FlagResult{true, "Success"}
+}
+
+// Chown takes user and group names or ids, an empty one is left unchanged.
+func (a *App) Chown(path string, owner string, group string) FlagResult {
+	log.Printf("Chown: %s %s:%s", path, owner, group)
+
+	fullPath, err := sandboxPath(path, sandboxWrite)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	uid, gid := -1, -1
+
+	if owner != "" {
+		if uid, err = strconv.Atoi(owner); err != nil {
+			u, err := user.Lookup(owner)
+			if err != nil {
+				return FlagResult{false, err.Error()}
+			}
+			if uid, err = strconv.Atoi(u.Uid); err != nil {
+				return FlagResult{false, "unsupported user id: " + u.Uid}
+			}
+		}
+	}
+
+	if group != "" {
+		if gid, err = strconv.Atoi(group); err != nil {
+			g, err := user.LookupGroup(group)
+			if err != nil {
+				return FlagResult{false, err.Error()}
+			}
+			if gid, err = strconv.Atoi(g.Gid); err != nil {
+				return FlagResult{false, "unsupported group id: " + g.Gid}
+			}
+		}
+	}
+
+	if err := os.Lchown(fullPath, uid, gid); err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	return FlagResult{true, "Success"}
+}
+
+// Symlink creates link pointing to target. A relative target is kept relative,
+// like ln -s does, and has to stay inside the sandbox as seen from the link.
+func (a *App) Symlink(target string, link string) FlagResult {
+	log.Printf("Symlink: %s -> %s", link, target)
+
+	policy, link, err := sandboxPolicyFor(link)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	linkPath := resolvePath(link)
+	if err := policy.check(link, linkPath, sandboxWrite); err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	targetPath := filepath.FromSlash(target)
+	if !filepath.IsAbs(targetPath) {
+		targetPath = filepath.Join(filepath.Dir(linkPath), targetPath)
+	}
+	if err := policy.check(target, filepath.ToSlash(filepath.Clean(targetPath)), sandboxRead); err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(linkPath), os.ModePerm); err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	if err := os.Symlink(filepath.FromSlash(target), linkPath); err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	return FlagResult{true, "Success"}
+}
+
+func (a *App) Readlink(path string) FlagResult {
+	log.Printf("Readlink: %s", path)
+
+	fullPath, err := sandboxPath(path, sandboxRead)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	target, err := os.Readlink(fullPath)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	return FlagResult{true, filepath.ToSlash(target)}
+}
+
+// parseFileMode applies an octal or symbolic mode to the permission bits of current.
+func parseFileMode(mode string, current fs.FileMode) (fs.FileMode, error) {
+	mode = strings.TrimSpace(mode)
+	if mode == "" {
+		return 0, errors.New("empty mode")
+	}
+
+	if v, err := strconv.ParseUint(mode, 8, 32); err == nil {
+		if v > 0777 {
+			return 0, errors.New("unsupported mode: " + mode)
+		}
+		return fs.FileMode(v), nil
+	}
+
+	perm := current.Perm()
+
+	for clause := range strings.SplitSeq(mode, ",") {
+		i := strings.IndexAny(clause, "+-=")
+		if i < 0 {
+			return 0, errors.New("invalid mode: " + mode)
+		}
+
+		var who fs.FileMode
+		for _, c := range clause[:i] {
+			switch c {
+			case 'u':
+				who |= 0700
+			case 'g':
+				who |= 0070
+			case 'o':
+				who |= 0007
+			case 'a':
+				who |= 0777
+			default:
+				return 0, errors.New("invalid mode: " + mode)
+			}
+		}
+		if who == 0 {
+			who = 0777
+		}
+
+		var bits fs.FileMode
+		for _, c := range clause[i+1:] {
+			switch c {
+			case 'r':
+				bits |= 0444
+			case 'w':
+				bits |= 0222
+			case 'x':
+				bits |= 0111
+			default:
+				return 0, errors.New("invalid mode: " + mode)
+			}
+		}
+		bits &= who
+
+		switch clause[i] {
+		case '+':
+			perm |= bits
+		case '-':
+			perm &^= bits
+		case '=':
+			perm = perm&^who | bits
+		}
+	}
+
+	return perm, nil
+}
@@ -0,0 +1,30 @@
+//go:build !windows
+
+package bridge
+
+import (
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// fileOwner returns the owner of a file, with names falling back to the ids.
+func fileOwner(path string, info os.FileInfo) (owner string, group string, uid int, gid int) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", "", -1, -1
+	}
+
+	uid, gid = int(stat.Uid), int(stat.Gid)
+	owner, group = strconv.Itoa(uid), strconv.Itoa(gid)
+
+	if u, err := user.LookupId(owner); err == nil {
+		owner = u.Username
+	}
+	if g, err := user.LookupGroupId(group); err == nil {
+		group = g.Name
+	}
+
+	return owner, group, uid, gid
+}
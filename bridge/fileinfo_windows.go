@@ -0,0 +1,37 @@
+//go:build windows
+
+package bridge
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// fileOwner returns the accounts owning a file. Windows has no numeric ids.
+func fileOwner(path string, info os.FileInfo) (owner string, group string, uid int, gid int) {
+	sd, err := windows.GetNamedSecurityInfo(path, windows.SE_FILE_OBJECT, windows.OWNER_SECURITY_INFORMATION|windows.GROUP_SECURITY_INFORMATION)
+	if err != nil {
+		return "", "", -1, -1
+	}
+
+	if sid, _, err := sd.Owner(); err == nil {
+		owner = accountName(sid)
+	}
+	if sid, _, err := sd.Group(); err == nil {
+		group = accountName(sid)
+	}
+
+	return owner, group, -1, -1
+}
+
+func accountName(sid *windows.SID) string {
+	account, domain, _, err := sid.LookupAccount("")
+	if err != nil {
+		return sid.String()
+	}
+	if domain != "" {
+		return domain + `\` + account
+	}
+	return account
+}
@@ -0,0 +1,242 @@
+package bridge
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/netip"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Firewall rules are built from fixed templates like routes and kept apart from the
+// rest of the ruleset: on linux they carry the app name as iptables comment, on darwin
+// they live in an anchor of their own below com.apple/, which the stock pf.conf
+// evaluates. pf itself has to be enabled, e.g. with pfctl -E.
+
+type helperFirewallParams struct {
+	Action string              `json:"action"`
+	Rule   FirewallRuleOptions `json:"rule"`
+}
+
+var firewallTargets = map[string]string{"accept": "ACCEPT", "drop": "DROP", "reject": "REJECT"}
+
+var pfActions = map[string]string{"accept": "pass", "drop": "block drop", "reject": "block return"}
+
+func (a *App) AddFirewallRule(rule FirewallRuleOptions) FlagResult {
+	log.Printf("AddFirewallRule: %v", rule)
+
+	return runRouteOperation("firewall", helperFirewallParams{"add", rule})
+}
+
+func (a *App) DeleteFirewallRule(rule FirewallRuleOptions) FlagResult {
+	log.Printf("DeleteFirewallRule: %v", rule)
+
+	return runRouteOperation("firewall", helperFirewallParams{"delete", rule})
+}
+
+func helperFirewall(owner uint32, raw json.RawMessage, files []*os.File) (string, error) {
+	var params helperFirewallParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return "", err
+	}
+
+	if params.Action != "add" && params.Action != "delete" {
+		return "", errors.New("invalid action: " + params.Action)
+	}
+
+	rule, err := validateFirewallRule(params.Rule)
+	if err != nil {
+		return "", err
+	}
+
+	switch Env.OS {
+	case "linux":
+		name, args := iptablesCommand(params.Action, rule)
+		return runNetworkCommand(name, args)
+	case "darwin":
+		return updatePfAnchor(params.Action, pfRule(rule))
+	}
+
+	return "", errors.New("firewall rules are not supported on " + Env.OS)
+}
+
+// validateFirewallRule checks every field that ends up in a command and returns the
+// rule with its networks in canonical form.
+func validateFirewallRule(rule FirewallRuleOptions) (FirewallRuleOptions, error) {
+	if _, ok := firewallTargets[rule.Action]; !ok {
+		return rule, errors.New("invalid firewall action: " + rule.Action)
+	}
+	if rule.Direction != "in" && rule.Direction != "out" {
+		return rule, errors.New("invalid direction: " + rule.Direction)
+	}
+	if rule.Protocol != "" && rule.Protocol != "tcp" && rule.Protocol != "udp" {
+		return rule, errors.New("invalid protocol: " + rule.Protocol)
+	}
+	if rule.Port < 0 || rule.Port > 65535 {
+		return rule, fmt.Errorf("invalid port: %d", rule.Port)
+	}
+	if rule.Port > 0 && rule.Protocol == "" {
+		return rule, errors.New("a port needs a protocol")
+	}
+	if rule.Device != "" && !interfaceNamePattern.MatchString(rule.Device) {
+		return rule, errors.New("invalid device: " + rule.Device)
+	}
+
+	for _, sel := range []struct {
+		key   string
+		value *string
+	}{{"source", &rule.Source}, {"destination", &rule.Destination}} {
+		if *sel.value == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(*sel.value)
+		if err != nil {
+			return rule, fmt.Errorf("invalid %s: %w", sel.key, err)
+		}
+		if prefix.Addr().Is6() != rule.IPv6 {
+			return rule, fmt.Errorf("%s does not match the rule family", sel.key)
+		}
+		*sel.value = prefix.Masked().String()
+	}
+
+	return rule, nil
+}
+
+func iptablesCommand(action string, rule FirewallRuleOptions) (string, []string) {
+	name := "iptables"
+	if rule.IPv6 {
+		name = "ip6tables"
+	}
+
+	// Inserted rather than appended, so they are not shadowed by existing rules.
+	args := []string{"-w", "-I", "INPUT"}
+	if action == "delete" {
+		args[1] = "-D"
+	}
+	if rule.Direction == "out" {
+		args[2] = "OUTPUT"
+	}
+
+	if rule.Device != "" {
+		if rule.Direction == "in" {
+			args = append(args, "-i", rule.Device)
+		} else {
+			args = append(args, "-o", rule.Device)
+		}
+	}
+	if rule.Protocol != "" {
+		args = append(args, "-p", rule.Protocol)
+	}
+	if rule.Port > 0 {
+		args = append(args, "--dport", strconv.Itoa(rule.Port))
+	}
+	if rule.Source != "" {
+		args = append(args, "-s", rule.Source)
+	}
+	if rule.Destination != "" {
+		args = append(args, "-d", rule.Destination)
+	}
+	args = append(args, "-m", "comment", "--comment", firewallTag(), "-j", firewallTargets[rule.Action])
+
+	return name, args
+}
+
+func pfRule(rule FirewallRuleOptions) string {
+	parts := []string{pfActions[rule.Action], rule.Direction, "quick"}
+	if rule.Device != "" {
+		parts = append(parts, "on", rule.Device)
+	}
+	if rule.IPv6 {
+		parts = append(parts, "inet6")
+	} else {
+		parts = append(parts, "inet")
+	}
+	if rule.Protocol != "" {
+		parts = append(parts, "proto", rule.Protocol)
+	}
+
+	source, destination := "any", "any"
+	if rule.Source != "" {
+		source = rule.Source
+	}
+	if rule.Destination != "" {
+		destination = rule.Destination
+	}
+	parts = append(parts, "from", source, "to", destination)
+	if rule.Port > 0 {
+		parts = append(parts, "port", strconv.Itoa(rule.Port))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// updatePfAnchor adds or deletes a rule by reloading the anchor. Rules are compared the
+// way pfctl prints them, so the new one is parsed by pfctl first.
+func updatePfAnchor(action string, rule string) (string, error) {
+	anchor := "com.apple/" + firewallTag()
+
+	normalized, err := runPfctl(rule+"\n", "-a", anchor, "-nvf", "-")
+	if err != nil {
+		return "", err
+	}
+	if normalized == "" || strings.Contains(normalized, "\n") {
+		return "", errors.New("unexpected pfctl output: " + normalized)
+	}
+
+	current, err := runPfctl("", "-a", anchor, "-sr")
+	if err != nil {
+		return "", err
+	}
+	rules := []string{}
+	for _, line := range strings.Split(current, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			rules = append(rules, line)
+		}
+	}
+
+	index := slices.Index(rules, normalized)
+	switch {
+	case action == "add" && index < 0:
+		rules = append(rules, normalized)
+	case action == "delete" && index >= 0:
+		rules = slices.Delete(rules, index, index+1)
+	case action == "delete":
+		return "", errors.New("firewall rule not found")
+	default:
+		return "Success", nil
+	}
+
+	if _, err := runPfctl(strings.Join(rules, "\n")+"\n", "-a", anchor, "-f", "-"); err != nil {
+		return "", err
+	}
+
+	return "Success", nil
+}
+
+func runPfctl(input string, args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("pfctl", args...)
+	cmd.Stdin = strings.NewReader(input)
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		output := strings.TrimSpace(stderr.String())
+		if output == "" {
+			output = err.Error()
+		}
+		return "", errors.New(output)
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+func firewallTag() string {
+	return strings.TrimSuffix(Env.AppName, ".exe")
+}
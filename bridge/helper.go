@@ -0,0 +1,504 @@
+package bridge
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The privileged helper is this binary started as root with the "helper" argument.
+// It serves an allowlisted set of operations on a Unix socket that only the user
+// running the GUI may connect to.
+
+const (
+	helperMaxRequestSize = 1 << 20
+	helperMaxFiles       = 4 // descriptors accepted with a request
+)
+
+var helperStartMu sync.Mutex
+
+// Capabilities the helper grants to cores it starts, nothing beyond networking.
+var helperCapabilities = []string{"CAP_NET_ADMIN", "CAP_NET_BIND_SERVICE", "CAP_NET_RAW"}
+
+type helperRequest struct {
+	Op     string          `json:"op"`
+	Params json.RawMessage `json:"params"`
+}
+
+type helperDNSParams struct {
+	Servers  string   `json:"servers"`
+	Services []string `json:"services"`
+}
+
+type helperExecParams struct {
+	Path         string   `json:"path"`
+	Args         []string `json:"args"`
+	Capabilities []string `json:"capabilities"`
+}
+
+// Operations receive the files passed along with a request and must not keep them.
+var helperOperations = map[string]func(owner uint32, params json.RawMessage, files []*os.File) (string, error){
+	"setDNS":          helperSetDNS,
+	"writeResolvConf": helperWriteResolvConf,
+	"exec":            helperExec,
+	"route":           helperRoute,
+	"routeRule":       helperRouteRule,
+	"firewall":        helperFirewall,
+}
+
+func (a *App) StartHelper() FlagResult {
+	log.Printf("StartHelper")
+
+	if Env.IsPrivileged {
+		return FlagResult{true, "Success"}
+	}
+
+	if err := ensureHelper(); err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	return FlagResult{true, "Success"}
+}
+
+func (a *App) StopHelper() FlagResult {
+	log.Printf("StopHelper")
+
+	if _, err := sendHelperRequest("shutdown", nil); err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	return FlagResult{true, "Success"}
+}
+
+func (a *App) WriteResolvConf(content string) FlagResult {
+	log.Printf("WriteResolvConf: %d bytes", len(content))
+
+	if _, err := runPrivileged("writeResolvConf", content); err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	return FlagResult{true, "Success"}
+}
+
+// ExecPrivileged starts a core with networking capabilities through the helper.
+// The core runs as the current user, so it can be managed and killed like any other
+// process, but its output is only available through LogFile. Linux only, elsewhere
+// there are no capabilities to hand out.
+func (a *App) ExecPrivileged(path string, args []string, options PrivilegedExecOptions) FlagResult {
+	log.Printf("ExecPrivileged: %s %s %v", path, args, options)
+
+	// Fail before the helper asks for a password it could not make use of.
+	if Env.OS != "linux" {
+		return FlagResult{false, "ExecPrivileged is not supported on " + Env.OS}
+	}
+
+	fullPath, err := sandboxPath(path, sandboxRead)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	pidPath := ""
+	if options.PidFile != "" {
+		if pidPath, err = sandboxPath(options.PidFile, sandboxWrite); err != nil {
+			return FlagResult{false, err.Error()}
+		}
+	}
+
+	// The helper never opens files for us, the log is opened here with our own
+	// permissions and handed over as a descriptor.
+	files := []*os.File{}
+	if options.LogFile != "" {
+		logPath, err := sandboxPath(options.LogFile, sandboxWrite)
+		if err != nil {
+			return FlagResult{false, err.Error()}
+		}
+		if err := os.MkdirAll(filepath.Dir(logPath), os.ModePerm); err != nil {
+			return FlagResult{false, err.Error()}
+		}
+		logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+		if err != nil {
+			return FlagResult{false, err.Error()}
+		}
+		defer logFile.Close()
+		files = append(files, logFile)
+	}
+
+	pid, err := runPrivileged("exec", helperExecParams{fullPath, args, options.Capabilities}, files...)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	if pidPath != "" {
+		if err := os.MkdirAll(filepath.Dir(pidPath), os.ModePerm); err != nil {
+			log.Printf("ExecPrivileged: pid file: %v", err)
+		} else if err := os.WriteFile(pidPath, []byte(pid), 0644); err != nil {
+			log.Printf("ExecPrivileged: pid file: %v", err)
+		}
+	}
+
+	return FlagResult{true, pid}
+}
+
+// runPrivileged performs an operation in process when we already are privileged and
+// through the helper otherwise. The caller keeps ownership of files.
+func runPrivileged(op string, params any, files ...*os.File) (string, error) {
+	if Env.IsPrivileged {
+		b, err := json.Marshal(params)
+		if err != nil {
+			return "", err
+		}
+		return helperOperations[op](uint32(os.Getuid()), b, files)
+	}
+
+	if err := ensureHelper(); err != nil {
+		return "", err
+	}
+
+	return sendHelperRequest(op, params, files...)
+}
+
+func helperSocketPath(owner uint32) string {
+	return fmt.Sprintf("/var/run/%s-helper-%d.sock", strings.TrimSuffix(Env.AppName, ".exe"), owner)
+}
+
+func ensureHelper() error {
+	helperStartMu.Lock()
+	defer helperStartMu.Unlock()
+
+	if conn, err := net.DialTimeout("unix", helperSocketPath(uint32(os.Getuid())), time.Second); err == nil {
+		conn.Close()
+		return nil
+	}
+
+	exePath, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	args := []string{
+		"helper",
+		"--owner", strconv.Itoa(os.Getuid()),
+		"--parent", strconv.Itoa(os.Getpid()),
+	}
+	if err := launchHelper(exePath, args); err != nil {
+		return fmt.Errorf("failed to start privileged helper: %w", err)
+	}
+
+	// Give the user time to answer the authentication prompt.
+	deadline := time.Now().Add(2 * time.Minute)
+	for time.Now().Before(deadline) {
+		if conn, err := net.DialTimeout("unix", helperSocketPath(uint32(os.Getuid())), time.Second); err == nil {
+			conn.Close()
+			return nil
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	return errors.New("privileged helper did not start")
+}
+
+func sendHelperRequest(op string, params any, files ...*os.File) (string, error) {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return "", err
+	}
+
+	line, err := json.Marshal(helperRequest{op, raw})
+	if err != nil {
+		return "", err
+	}
+
+	conn, err := net.DialTimeout("unix", helperSocketPath(uint32(os.Getuid())), 5*time.Second)
+	if err != nil {
+		return "", fmt.Errorf("privileged helper is not running: %w", err)
+	}
+	defer conn.Close()
+
+	if err := writeHelperMessage(conn.(*net.UnixConn), append(line, '\n'), files); err != nil {
+		return "", err
+	}
+
+	var result FlagResult
+	if err := json.NewDecoder(conn).Decode(&result); err != nil {
+		return "", fmt.Errorf("privileged helper: %w", err)
+	}
+	if !result.Flag {
+		return "", errors.New(result.Data)
+	}
+
+	return result.Data, nil
+}
+
+// RunHelper serves privileged operations until the GUI that started it exits.
+func RunHelper(args []string) error {
+	flags := flag.NewFlagSet("helper", flag.ContinueOnError)
+	owner := flags.Uint("owner", 0, "uid allowed to connect")
+	parent := flags.Int("parent", 0, "pid of the GUI, the helper exits with it")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if priv, err := IsPrivileged(); err != nil || !priv {
+		return errors.New("the helper must run as root")
+	}
+
+	exePath, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	Env.BasePath = filepath.ToSlash(filepath.Dir(exePath))
+	Env.AppName = filepath.Base(exePath)
+	Env.IsPrivileged = true
+
+	socketPath := helperSocketPath(uint32(*owner))
+	listener, err := listenHelperSocket(socketPath, uint32(*owner))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(socketPath)
+
+	if *parent > 0 {
+		go func() {
+			for {
+				time.Sleep(5 * time.Second)
+				if p, err := os.FindProcess(*parent); err == nil {
+					if alive, err := IsProcessAlive(p); err == nil && alive {
+						continue
+					}
+				}
+				log.Printf("Helper: GUI %d exited", *parent)
+				listener.Close()
+				return
+			}
+		}()
+	}
+
+	log.Printf("Helper: listening on %s for uid %d", socketPath, *owner)
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go serveHelperConn(conn.(*net.UnixConn), uint32(*owner), listener)
+	}
+}
+
+func serveHelperConn(conn *net.UnixConn, owner uint32, listener net.Listener) {
+	defer conn.Close()
+
+	uid, err := helperPeerUID(conn)
+	if err != nil || (uid != owner && uid != 0) {
+		log.Printf("Helper: rejected connection from uid %d: %v", uid, err)
+		return
+	}
+
+	reader := &helperReader{conn: conn}
+	defer reader.close()
+	encoder := json.NewEncoder(conn)
+
+	for {
+		line, files, err := reader.next()
+		if err != nil {
+			return
+		}
+
+		result, last := serveHelperRequest(owner, line, files, listener)
+		for _, f := range files {
+			f.Close()
+		}
+		_ = encoder.Encode(result)
+		if last {
+			return
+		}
+	}
+}
+
+// serveHelperRequest runs one request and reports whether the connection is done.
+func serveHelperRequest(owner uint32, line []byte, files []*os.File, listener net.Listener) (FlagResult, bool) {
+	var req helperRequest
+	if err := json.Unmarshal(line, &req); err != nil {
+		return FlagResult{false, err.Error()}, true
+	}
+
+	if req.Op == "shutdown" {
+		listener.Close()
+		return FlagResult{true, "Success"}, true
+	}
+
+	handler, ok := helperOperations[req.Op]
+	if !ok {
+		return FlagResult{false, "operation not allowed: " + req.Op}, false
+	}
+
+	log.Printf("Helper: %s", req.Op)
+	data, err := handler(owner, req.Params, files)
+	if err != nil {
+		return FlagResult{false, err.Error()}, false
+	}
+
+	return FlagResult{true, data}, false
+}
+
+// helperReader splits a connection into requests, each being a line of JSON that
+// may come with files passed as descriptors.
+type helperReader struct {
+	conn  *net.UnixConn
+	buf   []byte
+	files []*os.File
+}
+
+func (r *helperReader) next() ([]byte, []*os.File, error) {
+	chunk := make([]byte, 64*1024)
+	for {
+		if i := bytes.IndexByte(r.buf, '\n'); i >= 0 {
+			line := append([]byte(nil), r.buf[:i]...)
+			r.buf = r.buf[i+1:]
+			files := r.files
+			r.files = nil
+			return line, files, nil
+		}
+		if len(r.buf) > helperMaxRequestSize {
+			return nil, nil, errors.New("request too large")
+		}
+
+		n, files, err := readHelperMessage(r.conn, chunk)
+		r.files = append(r.files, files...)
+		if err != nil {
+			return nil, nil, err
+		}
+		r.buf = append(r.buf, chunk[:n]...)
+	}
+}
+
+func (r *helperReader) close() {
+	for _, f := range r.files {
+		f.Close()
+	}
+}
+
+func helperSetDNS(owner uint32, raw json.RawMessage, files []*os.File) (string, error) {
+	var params helperDNSParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return "", err
+	}
+
+	for _, server := range splitCommaSeparated(params.Servers) {
+		if net.ParseIP(server) == nil {
+			return "", fmt.Errorf("invalid DNS server: %s", server)
+		}
+	}
+
+	switch Env.OS {
+	case "darwin":
+		return "Success", setDarwinSystemDNS(params.Servers, params.Services)
+	case "linux":
+		return "Success", setLinuxSystemDNS(params.Servers, params.Services)
+	}
+
+	return "Success", nil
+}
+
+func helperWriteResolvConf(owner uint32, raw json.RawMessage, files []*os.File) (string, error) {
+	var content string
+	if err := json.Unmarshal(raw, &content); err != nil {
+		return "", err
+	}
+
+	// Write through the file rather than replacing it, /etc/resolv.conf is often a
+	// symlink managed by the resolver daemon.
+	if err := os.WriteFile("/etc/resolv.conf", []byte(content), 0644); err != nil {
+		return "", err
+	}
+
+	return "Success", nil
+}
+
+// helperExec starts an executable shipped next to the GUI as the owner with networking
+// capabilities. Nothing but the executable and its arguments is taken from the client:
+// the core gets a clean environment, and its output goes to the descriptor passed as
+// first file, which the client opened with its own permissions.
+func helperExec(owner uint32, raw json.RawMessage, files []*os.File) (string, error) {
+	var params helperExecParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return "", err
+	}
+
+	if Env.OS != "linux" {
+		return "", errors.New("exec is not supported on " + Env.OS)
+	}
+
+	exePath, err := filepath.EvalSymlinks(resolvePath(params.Path))
+	if err != nil {
+		return "", err
+	}
+	basePath, err := filepath.EvalSymlinks(Env.BasePath)
+	if err != nil {
+		return "", err
+	}
+	if rel, err := filepath.Rel(basePath, exePath); err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", fmt.Errorf("%s is outside of %s", params.Path, Env.BasePath)
+	}
+	if stat, err := os.Stat(exePath); err != nil || !stat.Mode().IsRegular() {
+		return "", fmt.Errorf("%s is not an executable", params.Path)
+	}
+
+	caps := params.Capabilities
+	if len(caps) == 0 {
+		caps = helperCapabilities
+	}
+	for _, c := range caps {
+		name := strings.ToUpper(c)
+		if !strings.HasPrefix(name, "CAP_") {
+			name = "CAP_" + name
+		}
+		if !slices.Contains(helperCapabilities, name) {
+			return "", fmt.Errorf("capability not allowed: %s", c)
+		}
+	}
+
+	env, err := buildCommandEnv(ExecOptions{CleanEnv: true})
+	if err != nil {
+		return "", err
+	}
+
+	cmd := exec.Command(exePath, params.Args...)
+	cmd.Dir = filepath.Dir(exePath)
+	cmd.Env = env.list()
+	if len(files) > 0 {
+		cmd.Stdout = files[0]
+		cmd.Stderr = files[0]
+	}
+
+	credential := CredentialOptions{User: strconv.FormatUint(uint64(owner), 10), Capabilities: caps}
+	if err := applyCredential(cmd, credential); err != nil {
+		return "", err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return "", err
+	}
+
+	go func() {
+		if err := cmd.Wait(); err != nil {
+			log.Printf("Helper: %s exited: %v", exePath, err)
+		}
+	}()
+
+	return strconv.Itoa(cmd.Process.Pid), nil
+}
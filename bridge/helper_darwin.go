@@ -0,0 +1,46 @@
+//go:build darwin
+
+package bridge
+
+import (
+	"net"
+	"os/exec"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+func helperPeerUID(conn *net.UnixConn) (uint32, error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+
+	var cred *unix.Xucred
+	var credErr error
+	err = raw.Control(func(fd uintptr) {
+		cred, credErr = unix.GetsockoptXucred(int(fd), unix.SOL_LOCAL, unix.LOCAL_PEERCRED)
+	})
+	if err != nil {
+		return 0, err
+	}
+	if credErr != nil {
+		return 0, credErr
+	}
+
+	return cred.Uid, nil
+}
+
+// launchHelper asks for an administrator password and leaves the helper running
+// in the background.
+func launchHelper(exePath string, args []string) error {
+	quoted := make([]string, 0, len(args)+1)
+	for _, arg := range append([]string{exePath}, args...) {
+		quoted = append(quoted, "'"+strings.ReplaceAll(arg, "'", `'\''`)+"'")
+	}
+
+	shell := strings.Join(quoted, " ") + " > /dev/null 2>&1 &"
+	script := `do shell script "` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(shell) + `" with administrator privileges`
+
+	return exec.Command("osascript", "-e", script).Run()
+}
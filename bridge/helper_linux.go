@@ -0,0 +1,46 @@
+//go:build linux
+
+package bridge
+
+import (
+	"net"
+	"os/exec"
+
+	"golang.org/x/sys/unix"
+)
+
+func helperPeerUID(conn *net.UnixConn) (uint32, error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+
+	var cred *unix.Ucred
+	var credErr error
+	err = raw.Control(func(fd uintptr) {
+		cred, credErr = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	})
+	if err != nil {
+		return 0, err
+	}
+	if credErr != nil {
+		return 0, credErr
+	}
+
+	return cred.Uid, nil
+}
+
+// launchHelper asks for authorization through polkit, pkexec keeps running as the
+// helper's parent until it exits.
+func launchHelper(exePath string, args []string) error {
+	cmd := exec.Command("pkexec", append([]string{exePath}, args...)...)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	go func() {
+		_ = cmd.Wait()
+	}()
+
+	return nil
+}
@@ -0,0 +1,17 @@
+//go:build !linux && !darwin && !windows
+
+package bridge
+
+import (
+	"errors"
+	"net"
+	"runtime"
+)
+
+func helperPeerUID(conn *net.UnixConn) (uint32, error) {
+	return 0, errors.New("the privileged helper is not supported on " + runtime.GOOS)
+}
+
+func launchHelper(exePath string, args []string) error {
+	return errors.New("the privileged helper is not supported on " + runtime.GOOS + ", run the GUI as root")
+}
@@ -0,0 +1,81 @@
+//go:build !windows
+
+package bridge
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"syscall"
+)
+
+func listenHelperSocket(path string, owner uint32) (net.Listener, error) {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	// Create the socket inaccessible and only then hand it to the owner.
+	mask := syscall.Umask(0177)
+	listener, err := net.Listen("unix", path)
+	syscall.Umask(mask)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Chown(path, int(owner), -1); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	return listener, nil
+}
+
+// writeHelperMessage sends data with files attached as descriptors.
+func writeHelperMessage(conn *net.UnixConn, data []byte, files []*os.File) error {
+	var oob []byte
+	if len(files) > 0 {
+		fds := make([]int, len(files))
+		for i, f := range files {
+			fds[i] = int(f.Fd())
+		}
+		oob = syscall.UnixRights(fds...)
+	}
+
+	n, _, err := conn.WriteMsgUnix(data, oob, nil)
+	if err != nil {
+		return err
+	}
+	if n < len(data) {
+		_, err = conn.Write(data[n:])
+	}
+	return err
+}
+
+// readHelperMessage reads into buf and returns the files that came with the data.
+func readHelperMessage(conn *net.UnixConn, buf []byte) (int, []*os.File, error) {
+	oob := make([]byte, syscall.CmsgSpace(4*helperMaxFiles))
+
+	n, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+
+	var files []*os.File
+	if oobn > 0 {
+		if msgs, perr := syscall.ParseSocketControlMessage(oob[:oobn]); perr == nil {
+			for _, msg := range msgs {
+				fds, perr := syscall.ParseUnixRights(&msg)
+				if perr != nil {
+					continue
+				}
+				for _, fd := range fds {
+					syscall.CloseOnExec(fd)
+					files = append(files, os.NewFile(uintptr(fd), "helper-fd"))
+				}
+			}
+		}
+	}
+
+	if err == nil && n == 0 {
+		err = io.EOF
+	}
+	return n, files, err
+}
@@ -0,0 +1,29 @@
+//go:build windows
+
+package bridge
+
+import (
+	"errors"
+	"net"
+	"os"
+)
+
+func listenHelperSocket(path string, owner uint32) (net.Listener, error) {
+	return nil, errors.New("the privileged helper is not supported on windows")
+}
+
+func helperPeerUID(conn *net.UnixConn) (uint32, error) {
+	return 0, errors.New("the privileged helper is not supported on windows")
+}
+
+func launchHelper(exePath string, args []string) error {
+	return errors.New("the privileged helper is not supported on windows, run the GUI as administrator")
+}
+
+func writeHelperMessage(conn *net.UnixConn, data []byte, files []*os.File) error {
+	return errors.New("the privileged helper is not supported on windows")
+}
+
+func readHelperMessage(conn *net.UnixConn, buf []byte) (int, []*os.File, error) {
+	return 0, nil, errors.New("the privileged helper is not supported on windows")
+}
@@ -24,14 +24,22 @@ const (
 func (a *App) WriteFile(path string, content string, options IOOptions) FlagResult {
 	log.Printf("WriteFile [%s %s]: %s", options.Mode, options.Range, path)
 
-	fullPath := resolvePath(path)
+	fullPath, err := sandboxPath(path, sandboxWrite)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
 
 	if err := os.MkdirAll(filepath.Dir(fullPath), os.ModePerm); err != nil {
 		return FlagResult{false, err.Error()}
 	}
 
+	unlock, err := lockForIO(fullPath, options)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+	defer unlock()
+
 	var data []byte
-	var err error
 
 	switch options.Mode {
 	case Text:
@@ -45,6 +53,19 @@ func (a *App) WriteFile(path string, content string, options IOOptions) FlagResu
 		return FlagResult{false, "Unsupported IO mode: " + options.Mode}
 	}
 
+	if options.Backups > 0 && options.Range == "" {
+		if err := rotateBackups(fullPath, options.Backups); err != nil {
+			return FlagResult{false, err.Error()}
+		}
+	}
+
+	if options.Range == "" && !options.InPlace {
+		if err := writeFileAtomic(fullPath, data); err != nil {
+			return FlagResult{false, err.Error()}
+		}
+		return FlagResult{true, "Success"}
+	}
+
 	file, err := os.OpenFile(fullPath, os.O_RDWR|os.O_CREATE, 0644)
 	if err != nil {
 		return FlagResult{false, err.Error()}
@@ -89,7 +110,16 @@ func (a *App) WriteFile(path string, content string, options IOOptions) FlagResu
 func (a *App) ReadFile(path string, options IOOptions) FlagResult {
 	log.Printf("ReadFile [%s %s]: %s", options.Mode, options.Range, path)
 
-	fullPath := resolvePath(path)
+	fullPath, err := sandboxPath(path, sandboxRead)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	unlock, err := lockForIO(fullPath, options)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+	defer unlock()
 
 	file, err := os.Open(fullPath)
 	if err != nil {
@@ -133,8 +163,14 @@ func (a *App) ReadFile(path string, options IOOptions) FlagResult {
 func (a *App) MoveFile(source string, target string) FlagResult {
 	log.Printf("MoveFile: %s -> %s", source, target)
 
-	fullSource := resolvePath(source)
-	fullTarget := resolvePath(target)
+	fullSource, err := sandboxPath(source, sandboxRemove)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+	fullTarget, err := sandboxPath(target, sandboxWrite)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
 
 	if err := os.MkdirAll(filepath.Dir(fullTarget), os.ModePerm); err != nil {
 		return FlagResult{false, err.Error()}
@@ -150,7 +186,21 @@ func (a *App) MoveFile(source string, target string) FlagResult {
 func (a *App) RemoveFile(path string) FlagResult {
 	log.Printf("RemoveFile: %s", path)
 
-	fullPath := resolvePath(path)
+	fullPath, err := sandboxPath(path, sandboxRemove)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	// Deleting from the trash itself is final.
+	if Config.Trash.Enabled && !pathWithin(resolvePath("data/.trash"), fullPath) {
+		if _, err := os.Lstat(fullPath); os.IsNotExist(err) {
+			return FlagResult{true, "Success"}
+		}
+		if _, err := moveToTrash(fullPath); err != nil {
+			return FlagResult{false, err.Error()}
+		}
+		return FlagResult{true, "Success"}
+	}
 
 	if err := os.RemoveAll(fullPath); err != nil {
 		return FlagResult{false, err.Error()}
@@ -162,8 +212,14 @@ func (a *App) RemoveFile(path string) FlagResult {
 func (a *App) CopyFile(src string, dst string) FlagResult {
 	log.Printf("CopyFile: %s -> %s", src, dst)
 
-	srcPath := resolvePath(src)
-	dstPath := resolvePath(dst)
+	srcPath, err := sandboxPath(src, sandboxRead)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+	dstPath, err := sandboxPath(dst, sandboxWrite)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
 
 	srcFile, err := os.Open(srcPath)
 	if err != nil {
@@ -194,7 +250,10 @@ func (a *App) CopyFile(src string, dst string) FlagResult {
 func (a *App) MakeDir(path string) FlagResult {
 	log.Printf("MakeDir: %s", path)
 
-	fullPath := resolvePath(path)
+	fullPath, err := sandboxPath(path, sandboxWrite)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
 
 	if err := os.MkdirAll(fullPath, os.ModePerm); err != nil {
 		return FlagResult{false, err.Error()}
@@ -206,7 +265,10 @@ func (a *App) MakeDir(path string) FlagResult {
 func (a *App) ReadDir(path string) FlagResult {
 	log.Printf("ReadDir: %s", path)
 
-	fullPath := resolvePath(path)
+	fullPath, err := sandboxPath(path, sandboxRead)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
 
 	files, err := os.ReadDir(fullPath)
 	if err != nil {
@@ -227,9 +289,12 @@ func (a *App) ReadDir(path string) FlagResult {
 func (a *App) OpenDir(path string) FlagResult {
 	log.Printf("OpenDir: %s", path)
 
-	fullPath := resolvePath(path)
+	fullPath, err := sandboxPath(path, sandboxRead)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
 
-	err := browser.OpenURL(fullPath)
+	err = browser.OpenURL(fullPath)
 	if err != nil {
 		return FlagResult{false, err.Error()}
 	}
@@ -251,7 +316,10 @@ func (a *App) OpenURI(uri string) FlagResult {
 func (a *App) AbsolutePath(path string) FlagResult {
 	log.Printf("AbsolutePath: %s", path)
 
-	absPath := resolvePath(path)
+	absPath, err := sandboxPath(path, sandboxRead)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
 
 	return FlagResult{true, absPath}
 }
@@ -259,8 +327,14 @@ func (a *App) AbsolutePath(path string) FlagResult {
 func (a *App) UnzipZIPFile(path string, output string) FlagResult {
 	log.Printf("UnzipZIPFile: %s -> %s", path, output)
 
-	fullPath := resolvePath(path)
-	outputPath := resolvePath(output)
+	fullPath, err := sandboxPath(path, sandboxRead)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+	outputPath, err := sandboxPath(output, sandboxWrite)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
 
 	archive, err := zip.OpenReader(fullPath)
 	if err != nil {
@@ -312,8 +386,14 @@ func (a *App) UnzipZIPFile(path string, output string) FlagResult {
 func (a *App) UnzipTarGZFile(path string, output string) FlagResult {
 	log.Printf("UnzipTarGZFile: %s -> %s", path, output)
 
-	fullPath := resolvePath(path)
-	outputPath := resolvePath(output)
+	fullPath, err := sandboxPath(path, sandboxRead)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+	outputPath, err := sandboxPath(output, sandboxWrite)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
 
 	gzipFile, err := os.Open(fullPath)
 	if err != nil {
@@ -373,8 +453,14 @@ func (a *App) UnzipTarGZFile(path string, output string) FlagResult {
 func (a *App) UnzipGZFile(path string, output string) FlagResult {
 	log.Printf("UnzipGZFile: %s -> %s", path, output)
 
-	fullPath := resolvePath(path)
-	outputPath := resolvePath(output)
+	fullPath, err := sandboxPath(path, sandboxRead)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+	outputPath, err := sandboxPath(output, sandboxWrite)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
 
 	gzipFile, err := os.Open(fullPath)
 	if err != nil {
@@ -423,9 +509,12 @@ func archiveEntryPath(outputPath, entryName string) (string, bool) {
 func (a *App) FileExists(path string) FlagResult {
 	log.Printf("FileExists: %s", path)
 
-	path = resolvePath(path)
+	path, err := sandboxPath(path, sandboxRead)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
 
-	_, err := os.Stat(path)
+	_, err = os.Stat(path)
 	if err == nil {
 		return FlagResult{true, "true"}
 	}
@@ -440,7 +529,10 @@ func (a *App) FileExists(path string) FlagResult {
 func (a *App) FileSHA256(path string) FlagResult {
 	log.Printf("FileSHA256: %s", path)
 
-	fullPath := resolvePath(path)
+	fullPath, err := sandboxPath(path, sandboxRead)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
 
 	file, err := os.Open(fullPath)
 	if err != nil {
@@ -0,0 +1,369 @@
+//go:build linux
+
+package bridge
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+var (
+	cgroupMap     sync.Map
+	cgroupCounter atomic.Uint64
+)
+
+type cgroupHandle struct {
+	path string
+	dir  *os.File
+}
+
+// prepareResourceLimits arranges for every limit to be in place before the command
+// runs: the child is born inside its cgroup, and the open files limit and niceness are
+// set by the GUI binary re-executed in the child, right before it execs the command.
+func prepareResourceLimits(cmd *exec.Cmd, limits ResourceLimits) (*cgroupHandle, error) {
+	if err := validateResourceLimits(limits); err != nil {
+		return nil, err
+	}
+
+	if limits.MaxOpenFiles > 0 || limits.Nice != 0 {
+		if err := wrapWithLimits(cmd, limits); err != nil {
+			return nil, err
+		}
+	}
+
+	// Memory and CPU can only be capped by a cgroup.
+	if !limits.Cgroup && limits.MemoryMax <= 0 && limits.CPUQuota <= 0 {
+		return nil, nil
+	}
+
+	cg, err := createCgroup(limits)
+	if err != nil {
+		if limits.MemoryMax > 0 || limits.CPUQuota > 0 {
+			return nil, fmt.Errorf("memory and CPU limits need a cgroup: %w", err)
+		}
+		log.Printf("Failed to create cgroup, running without one: %v", err)
+		return nil, nil
+	}
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(cg.dir.Fd())
+
+	return cg, nil
+}
+
+func validateResourceLimits(limits ResourceLimits) error {
+	if limits.Nice < -20 || limits.Nice > 19 {
+		return errors.New("niceness must be between -20 and 19")
+	}
+	return nil
+}
+
+// wrapWithLimits runs the command through "<GUI> limits ... -- <path> <args>".
+func wrapWithLimits(cmd *exec.Cmd, limits ResourceLimits) error {
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	args := []string{self, "limits"}
+	if limits.MaxOpenFiles > 0 {
+		args = append(args, "-nofile", strconv.FormatUint(limits.MaxOpenFiles, 10))
+	}
+	if limits.Nice != 0 {
+		args = append(args, "-nice", strconv.Itoa(limits.Nice))
+	}
+	args = append(args, "--", cmd.Path)
+
+	cmd.Args = append(args, cmd.Args...)
+	cmd.Path = self
+
+	return nil
+}
+
+// RunWithLimits applies the limits to itself and execs the command in its place,
+// see wrapWithLimits.
+func RunWithLimits(args []string) error {
+	flags := flag.NewFlagSet("limits", flag.ContinueOnError)
+	nofile := flags.Uint64("nofile", 0, "open files limit")
+	nice := flags.Int("nice", 0, "niceness")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() < 2 {
+		return errors.New("usage: limits [-nofile n] [-nice n] -- path argv0 [args...]")
+	}
+
+	if *nofile > 0 {
+		rlimit := &unix.Rlimit{Cur: *nofile, Max: *nofile}
+		if err := unix.Setrlimit(unix.RLIMIT_NOFILE, rlimit); err != nil {
+			return fmt.Errorf("open files limit: %w", err)
+		}
+	}
+
+	// Niceness belongs to a thread on linux, the one calling execve becomes the
+	// only thread of the command.
+	runtime.LockOSThread()
+
+	if *nice != 0 {
+		if err := unix.Setpriority(unix.PRIO_PROCESS, 0, *nice); err != nil {
+			return fmt.Errorf("niceness: %w", err)
+		}
+	}
+
+	return syscall.Exec(flags.Arg(0), flags.Args()[1:], os.Environ())
+}
+
+func applyResourceLimits(pid int, cg *cgroupHandle) {
+	if cg == nil {
+		return
+	}
+
+	cg.dir.Close()
+	cgroupMap.Store(int32(pid), cg.path)
+}
+
+func releaseResourceLimits(pid int, cg *cgroupHandle) {
+	if cg == nil {
+		return
+	}
+
+	cg.dir.Close()
+	cgroupMap.Delete(int32(pid))
+
+	if err := os.Remove(cg.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("Failed to remove cgroup %s: %v", cg.path, err)
+	}
+}
+
+func createCgroup(limits ResourceLimits) (*cgroupHandle, error) {
+	root, err := cgroup2Mount()
+	if err != nil {
+		return nil, err
+	}
+
+	own, err := processCgroup(int32(os.Getpid()))
+	if err != nil {
+		return nil, err
+	}
+
+	// Our own group already holds processes, and cgroup v2 only allows processes in
+	// leaves, so the new group is created next to it inside the delegated slice.
+	parent := filepath.Join(root, filepath.Dir(own))
+
+	controllers := []string{}
+	if limits.MemoryMax > 0 {
+		controllers = append(controllers, "memory")
+	}
+	if limits.CPUQuota > 0 {
+		controllers = append(controllers, "cpu")
+	}
+	if err := enableCgroupControllers(parent, controllers); err != nil {
+		return nil, err
+	}
+
+	name := fmt.Sprintf("guiforcores-%d-%d", os.Getpid(), cgroupCounter.Add(1))
+	path := filepath.Join(parent, name)
+	if err := os.Mkdir(path, 0755); err != nil {
+		return nil, err
+	}
+
+	cleanup := func(err error) (*cgroupHandle, error) {
+		_ = os.Remove(path)
+		return nil, err
+	}
+
+	if limits.MemoryMax > 0 {
+		if err := writeCgroupFile(path, "memory.max", strconv.FormatInt(limits.MemoryMax, 10)); err != nil {
+			return cleanup(err)
+		}
+	}
+
+	if limits.CPUQuota > 0 {
+		// CPUQuota is a percentage of one CPU within a 100ms period.
+		quota := fmt.Sprintf("%d 100000", limits.CPUQuota*1000)
+		if err := writeCgroupFile(path, "cpu.max", quota); err != nil {
+			return cleanup(err)
+		}
+	}
+
+	dir, err := os.Open(path)
+	if err != nil {
+		return cleanup(err)
+	}
+
+	return &cgroupHandle{path: path, dir: dir}, nil
+}
+
+func enableCgroupControllers(path string, controllers []string) error {
+	if len(controllers) == 0 {
+		return nil
+	}
+
+	enabled, err := os.ReadFile(filepath.Join(path, "cgroup.subtree_control"))
+	if err != nil {
+		return err
+	}
+
+	fields := strings.Fields(string(enabled))
+	missing := []string{}
+	for _, controller := range controllers {
+		if !slices.Contains(fields, controller) {
+			missing = append(missing, "+"+controller)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	return writeCgroupFile(path, "cgroup.subtree_control", strings.Join(missing, " "))
+}
+
+func writeCgroupFile(dir string, name string, value string) error {
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(value), 0644); err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	return nil
+}
+
+func cgroup2Mount() (string, error) {
+	file, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// The filesystem type follows the " - " separator, the mount point is the fifth field.
+		fields := strings.Fields(scanner.Text())
+		for i, field := range fields {
+			if field == "-" && i+1 < len(fields) && fields[i+1] == "cgroup2" && len(fields) > 4 {
+				return fields[4], nil
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	return "", errors.New("cgroup v2 is not mounted")
+}
+
+func processCgroup(pid int32) (string, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/cgroup", pid))
+	if err != nil {
+		return "", err
+	}
+
+	for line := range strings.SplitSeq(string(data), "\n") {
+		if path, ok := strings.CutPrefix(line, "0::"); ok {
+			return path, nil
+		}
+	}
+
+	return "", errors.New("process is not in a cgroup v2 hierarchy")
+}
+
+func processCgroupPath(pid int32) (string, error) {
+	if value, ok := cgroupMap.Load(pid); ok {
+		return value.(string), nil
+	}
+
+	root, err := cgroup2Mount()
+	if err != nil {
+		return "", err
+	}
+
+	path, err := processCgroup(pid)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(root, path), nil
+}
+
+func processCgroupStats(pid int32) (map[string]any, error) {
+	path, err := processCgroupPath(pid)
+	if err != nil {
+		return nil, err
+	}
+
+	_, managed := cgroupMap.Load(pid)
+	stats := map[string]any{"path": path, "managed": managed}
+
+	readValue := func(name string) (string, bool) {
+		b, err := os.ReadFile(filepath.Join(path, name))
+		if err != nil {
+			return "", false
+		}
+		return strings.TrimSpace(string(b)), true
+	}
+
+	for _, name := range []string{"memory.current", "memory.peak", "pids.current"} {
+		if value, ok := readValue(name); ok {
+			if n, err := strconv.ParseUint(value, 10, 64); err == nil {
+				stats[name] = n
+			}
+		}
+	}
+
+	for _, name := range []string{"memory.max", "cpu.max"} {
+		if value, ok := readValue(name); ok {
+			stats[name] = value
+		}
+	}
+
+	if value, ok := readValue("cpu.stat"); ok {
+		for line := range strings.SplitSeq(value, "\n") {
+			key, raw, found := strings.Cut(line, " ")
+			if !found {
+				continue
+			}
+			if n, err := strconv.ParseUint(raw, 10, 64); err == nil {
+				stats["cpu."+key] = n
+			}
+		}
+	}
+
+	return stats, nil
+}
+
+// managedCgroupMemory reports the memory charged to a cgroup created for pid,
+// which includes every helper the process spawned.
+func managedCgroupMemory(pid int32) (uint64, bool) {
+	value, ok := cgroupMap.Load(pid)
+	if !ok {
+		return 0, false
+	}
+
+	b, err := os.ReadFile(filepath.Join(value.(string), "memory.current"))
+	if err != nil {
+		return 0, false
+	}
+
+	n, err := strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return n, true
+}
@@ -0,0 +1,39 @@
+//go:build !linux
+
+package bridge
+
+import (
+	"errors"
+	"os/exec"
+)
+
+type cgroupHandle struct{}
+
+func prepareResourceLimits(cmd *exec.Cmd, limits ResourceLimits) (*cgroupHandle, error) {
+	return nil, validateResourceLimits(limits)
+}
+
+func validateResourceLimits(limits ResourceLimits) error {
+	if limits != (ResourceLimits{}) {
+		return errors.New("resource limits are only supported on linux")
+	}
+	return nil
+}
+
+func applyResourceLimits(pid int, cg *cgroupHandle) {
+}
+
+func RunWithLimits(args []string) error {
+	return errors.New("resource limits are only supported on linux")
+}
+
+func releaseResourceLimits(pid int, cg *cgroupHandle) {
+}
+
+func processCgroupStats(pid int32) (map[string]any, error) {
+	return nil, errors.New("cgroups are only supported on linux")
+}
+
+func managedCgroupMemory(pid int32) (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,156 @@
+package bridge
+
+import (
+	"cmp"
+	"encoding/json"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+type dirEntry struct {
+	Name       string `json:"name"`
+	Path       string `json:"path"`
+	Size       int64  `json:"size"`
+	IsDir      bool   `json:"isDir"`
+	Mode       string `json:"mode"`
+	Perm       uint32 `json:"perm"`
+	ModTime    int64  `json:"modTime"`
+	IsSymlink  bool   `json:"isSymlink"`
+	LinkTarget string `json:"linkTarget,omitempty"`
+}
+
+type dirListing struct {
+	Entries []dirEntry `json:"entries"`
+	Total   int        `json:"total"`
+	Offset  int        `json:"offset"`
+}
+
+func (a *App) ListDir(path string, options ListDirOptions) FlagResult {
+	log.Printf("ListDir: %s %v", path, options)
+
+	policy, root, err := sandboxResolve(path, sandboxRead)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	maxDepth := 1
+	if options.Recursive {
+		maxDepth = options.MaxDepth
+	}
+
+	entries := []dirEntry{}
+
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == root {
+				return err
+			}
+			// Unreadable subdirectories should not hide everything else.
+			return nil
+		}
+		if p == root {
+			return nil
+		}
+		if policy.hides(p) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		depth := strings.Count(rel, "/") + 1
+
+		if matchPathGlob(options.Exclude, rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		descend := d.IsDir() && (maxDepth <= 0 || depth < maxDepth)
+
+		if d.IsDir() || len(options.Include) == 0 || matchPathGlob(options.Include, rel) {
+			info, err := d.Info()
+			if err == nil {
+				entry := dirEntry{
+					Name:      d.Name(),
+					Path:      rel,
+					Size:      info.Size(),
+					IsDir:     info.IsDir(),
+					Mode:      info.Mode().String(),
+					Perm:      uint32(info.Mode().Perm()),
+					ModTime:   info.ModTime().UnixMilli(),
+					IsSymlink: info.Mode()&os.ModeSymlink != 0,
+				}
+				if entry.IsSymlink {
+					entry.LinkTarget, _ = os.Readlink(p)
+					if stat, err := os.Stat(p); err == nil {
+						entry.IsDir = stat.IsDir()
+					}
+				}
+				entries = append(entries, entry)
+			}
+		}
+
+		if d.IsDir() && !descend {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	sortDirEntries(entries, options)
+
+	listing := dirListing{Total: len(entries), Offset: min(max(options.Offset, 0), len(entries))}
+	end := len(entries)
+	if options.Limit > 0 {
+		end = min(listing.Offset+options.Limit, end)
+	}
+	listing.Entries = entries[listing.Offset:end]
+
+	b, err := json.Marshal(listing)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	return FlagResult{true, string(b)}
+}
+
+func sortDirEntries(entries []dirEntry, options ListDirOptions) {
+	slices.SortStableFunc(entries, func(x, y dirEntry) int {
+		if options.DirsFirst && x.IsDir != y.IsDir {
+			if x.IsDir {
+				return -1
+			}
+			return 1
+		}
+
+		var c int
+		switch options.SortBy {
+		case "size":
+			c = cmp.Compare(x.Size, y.Size)
+		case "modTime":
+			c = cmp.Compare(x.ModTime, y.ModTime)
+		default:
+			c = strings.Compare(x.Path, y.Path)
+		}
+		if options.Desc {
+			c = -c
+		}
+		if c == 0 {
+			c = strings.Compare(x.Path, y.Path)
+		}
+		return c
+	})
+}
@@ -0,0 +1,143 @@
+package bridge
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const localFileRoute = "/__local/"
+
+var localFileMap sync.Map
+
+type localFile struct {
+	path    string
+	expires time.Time
+}
+
+// Types the system MIME tables commonly lack, the rest is left to http.ServeContent.
+var localFileTypes = map[string]string{
+	".yaml": "text/yaml; charset=utf-8",
+	".yml":  "text/yaml; charset=utf-8",
+	".log":  "text/plain; charset=utf-8",
+	".srs":  "application/octet-stream",
+	".mmdb": "application/octet-stream",
+	".db":   "application/octet-stream",
+}
+
+// CreateLocalFileURL mints a short-lived URL the webview can load path from directly,
+// instead of reading it through ReadFile as base64.
+func (a *App) CreateLocalFileURL(path string, options LocalFileOptions) FlagResult {
+	log.Printf("CreateLocalFileURL: %s %v", path, options)
+
+	policy, path, err := sandboxPolicyFor(path)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+	// The webview may only load data files, even for the app's privileged calls.
+	if policy == nil {
+		policy = &sandboxPolicy{name: "local files", roots: sandboxRoots()}
+	}
+
+	fullPath := resolvePath(path)
+	if err := policy.check(path, fullPath, sandboxRead); err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	stat, err := os.Stat(fullPath)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+	if stat.IsDir() {
+		return FlagResult{false, "path is a directory"}
+	}
+
+	ttl := time.Duration(options.TTL) * time.Second
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return FlagResult{false, err.Error()}
+	}
+	token := hex.EncodeToString(b)
+
+	now := time.Now()
+	localFileMap.Range(func(key, value any) bool {
+		if now.After(value.(*localFile).expires) {
+			localFileMap.Delete(key)
+		}
+		return true
+	})
+	localFileMap.Store(token, &localFile{fullPath, now.Add(ttl)})
+
+	// The file name only helps the webview, e.g. when saving the file.
+	return FlagResult{true, localFileRoute + token + "/" + url.PathEscape(filepath.Base(fullPath))}
+}
+
+func (a *App) RevokeLocalFileURL(token string) FlagResult {
+	log.Printf("RevokeLocalFileURL: %s", token)
+
+	token, _, _ = strings.Cut(strings.TrimPrefix(token, localFileRoute), "/")
+	if _, ok := localFileMap.LoadAndDelete(token); !ok {
+		return FlagResult{false, "token not found"}
+	}
+
+	return FlagResult{true, "Success"}
+}
+
+// LocalFiles serves the files of CreateLocalFileURL with Range support.
+func LocalFiles(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rest, ok := strings.CutPrefix(r.URL.Path, localFileRoute)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		token, _, _ := strings.Cut(rest, "/")
+		value, ok := localFileMap.Load(token)
+		if !ok || time.Now().After(value.(*localFile).expires) {
+			localFileMap.Delete(token)
+			http.NotFound(w, r)
+			return
+		}
+		path := value.(*localFile).path
+
+		file, err := os.Open(path)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer file.Close()
+
+		stat, err := file.Stat()
+		if err != nil || stat.IsDir() {
+			http.NotFound(w, r)
+			return
+		}
+
+		if contentType, ok := localFileTypes[strings.ToLower(filepath.Ext(path))]; ok {
+			w.Header().Set("Content-Type", contentType)
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		// Documents from the data directory must not run scripts in the origin of the app.
+		w.Header().Set("Content-Security-Policy", "sandbox")
+
+		http.ServeContent(w, r, filepath.Base(path), stat.ModTime(), file)
+	})
+}
@@ -0,0 +1,156 @@
+package bridge
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	fileLockMap     sync.Map
+	fileLockCounter atomic.Uint64
+)
+
+var errLockTimeout = errors.New("timed out waiting for file lock")
+
+type fileLock struct {
+	path string
+	file *os.File
+}
+
+// LockFile takes an advisory lock on path that other instances and callers of
+// LockFile, ReadFile and WriteFile respect until UnlockFile is called.
+func (a *App) LockFile(path string, options LockOptions) FlagResult {
+	log.Printf("LockFile: %s %v", path, options)
+
+	access := sandboxWrite
+	if options.Shared {
+		access = sandboxRead
+	}
+
+	fullPath, err := sandboxPath(path, access)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	file, err := acquireFileLock(fullPath, options.Shared, options.Timeout)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	id := "lock-" + strconv.FormatUint(fileLockCounter.Add(1), 10)
+	fileLockMap.Store(id, &fileLock{fullPath, file})
+
+	return FlagResult{true, id}
+}
+
+func (a *App) UnlockFile(id string) FlagResult {
+	log.Printf("UnlockFile: %s", id)
+
+	value, ok := fileLockMap.LoadAndDelete(id)
+	if !ok {
+		return FlagResult{false, "lock not found"}
+	}
+
+	if err := releaseFileLock(value.(*fileLock).file); err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	return FlagResult{true, "Success"}
+}
+
+// lockForIO takes the lock requested by options for a single ReadFile or WriteFile call.
+func lockForIO(fullPath string, options IOOptions) (func(), error) {
+	var shared bool
+	switch options.Lock {
+	case "":
+		return func() {}, nil
+	case "shared":
+		shared = true
+	case "exclusive":
+	default:
+		return nil, errors.New("Unsupported lock: " + options.Lock)
+	}
+
+	file, err := acquireFileLock(fullPath, shared, options.LockTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		if err := releaseFileLock(file); err != nil {
+			log.Printf("Failed to release lock of %s: %v", fullPath, err)
+		}
+	}, nil
+}
+
+// acquireFileLock locks a companion file of path. Locking path itself would not work,
+// atomic writes replace it with a new file the lock does not cover.
+func acquireFileLock(path string, shared bool, timeout int) (*os.File, error) {
+	lockPath, err := fileLockPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	if timeout <= 0 {
+		timeout = 10000
+	}
+	deadline := time.Now().Add(time.Duration(timeout) * time.Millisecond)
+
+	for {
+		locked, err := tryLockFile(file, shared)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		if locked {
+			return file, nil
+		}
+		if time.Now().After(deadline) {
+			file.Close()
+			return nil, errLockTimeout
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// fileLockPath names the companion file after a hash of path, in the cache directory of
+// the user so it never shows up next to the files it guards.
+func fileLockPath(path string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	dir = filepath.Join(dir, Env.AppName+"-locks")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+
+	path = filepath.ToSlash(filepath.Clean(path))
+	if Env.OS == "windows" {
+		path = strings.ToLower(path)
+	}
+	sum := sha256.Sum256([]byte(path))
+	return filepath.Join(dir, hex.EncodeToString(sum[:16])+".lock"), nil
+}
+
+func releaseFileLock(file *os.File) error {
+	if err := unlockFile(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,120 @@
+package bridge
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const LogParserSingBox = "sing-box"
+
+var logLevels = []string{"trace", "debug", "info", "warn", "error", "fatal", "panic"}
+
+var (
+	ansiPattern       = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+	singBoxLogPattern = regexp.MustCompile(`^(?:([+-]\d{4}) )?(?:(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}) )?(TRACE|DEBUG|INFO|WARN|ERROR|FATAL|PANIC) (?:\[(\d+)(?: ([^\]]+))?\] )?(.*)$`)
+	singBoxTagPattern = regexp.MustCompile(`^([\w-]+)/([\w-]+)\[([^\]]*)\]: (.*)$`)
+)
+
+type LogEntry struct {
+	Time     string `json:"time,omitempty"`
+	Level    string `json:"level"`
+	ID       string `json:"id,omitempty"`
+	Duration string `json:"duration,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Protocol string `json:"protocol,omitempty"`
+	Tag      string `json:"tag,omitempty"`
+	Message  string `json:"message"`
+}
+
+type logParser struct {
+	minLevel int
+}
+
+func newLogParser(name string, level string) (*logParser, error) {
+	switch name {
+	case "":
+		return nil, nil
+	case LogParserSingBox:
+	default:
+		return nil, errors.New("Unsupported log parser: " + name)
+	}
+
+	minLevel := 0
+	if level != "" {
+		minLevel = logLevelIndex(level)
+		if minLevel < 0 {
+			return nil, errors.New("Unsupported log level: " + level)
+		}
+	}
+
+	return &logParser{minLevel: minLevel}, nil
+}
+
+// parse turns a line of sing-box output into a LogEntry. It reports false when the
+// entry is below the configured level and should not be forwarded.
+func (lp *logParser) parse(text string) (LogEntry, bool) {
+	text = stripANSI(text)
+
+	match := singBoxLogPattern.FindStringSubmatch(text)
+	if match == nil {
+		// Lines without a level (stack traces, plain messages) are always forwarded.
+		return LogEntry{Message: text}, true
+	}
+
+	entry := LogEntry{
+		Time:     singBoxLogTime(match[1], match[2]),
+		Level:    strings.ToLower(match[3]),
+		ID:       match[4],
+		Duration: match[5],
+		Message:  match[6],
+	}
+
+	if logLevelIndex(entry.Level) < lp.minLevel {
+		return entry, false
+	}
+
+	if tag := singBoxTagPattern.FindStringSubmatch(entry.Message); tag != nil {
+		entry.Type = tag[1]
+		entry.Protocol = tag[2]
+		entry.Tag = tag[3]
+		entry.Message = tag[4]
+	}
+
+	return entry, true
+}
+
+func singBoxLogTime(zone string, datetime string) string {
+	if datetime == "" {
+		return ""
+	}
+	if zone == "" {
+		return datetime
+	}
+	t, err := time.Parse("-0700 2006-01-02 15:04:05", zone+" "+datetime)
+	if err != nil {
+		return datetime
+	}
+	return t.Format(time.RFC3339)
+}
+
+func logLevelIndex(level string) int {
+	level = strings.ToLower(level)
+	if level == "warning" {
+		level = "warn"
+	}
+	for i, l := range logLevels {
+		if l == level {
+			return i
+		}
+	}
+	return -1
+}
+
+func stripANSI(text string) string {
+	if !strings.Contains(text, "\x1b") {
+		return text
+	}
+	return ansiPattern.ReplaceAllString(text, "")
+}
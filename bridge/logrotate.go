@@ -0,0 +1,234 @@
+package bridge
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type rotatingLogFile struct {
+	path      string
+	options   LogRotateOptions
+	mu        sync.Mutex
+	file      *os.File
+	size      int64
+	openedAt  time.Time
+	lineStart bool
+}
+
+func logRotationEnabled(options LogRotateOptions) bool {
+	return options.MaxSize > 0 || options.MaxAge > 0
+}
+
+func openRotatingLogFile(path string, options LogRotateOptions) (*rotatingLogFile, error) {
+	if options.KeepPrevious {
+		if err := rotateLogBackups(path, options); err != nil {
+			return nil, err
+		}
+	}
+
+	r := &rotatingLogFile{path: path, options: options}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *rotatingLogFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	r.file = file
+	r.size = 0
+	r.openedAt = time.Now()
+	r.lineStart = true
+	return nil
+}
+
+func (r *rotatingLogFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	n := 0
+
+	if r.shouldRotate(len(p)) {
+		// Finish the current line in the old file so entries are never split across files.
+		if !r.lineStart {
+			i := bytes.IndexByte(p, '\n')
+			if i < 0 {
+				return r.write(p)
+			}
+			written, err := r.write(p[:i+1])
+			n += written
+			if err != nil {
+				return n, err
+			}
+			p = p[i+1:]
+		}
+
+		if err := r.rotate(); err != nil {
+			log.Printf("Failed to rotate log file %s: %v", r.path, err)
+		}
+	}
+
+	written, err := r.write(p)
+	return n + written, err
+}
+
+func (r *rotatingLogFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+func (r *rotatingLogFile) write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	if n > 0 {
+		r.lineStart = p[n-1] == '\n'
+	}
+	return n, err
+}
+
+func (r *rotatingLogFile) shouldRotate(pending int) bool {
+	if r.size == 0 {
+		return false
+	}
+	if r.options.MaxSize > 0 && r.size+int64(pending) > r.options.MaxSize {
+		return true
+	}
+	if r.options.MaxAge > 0 && time.Since(r.openedAt) >= time.Duration(r.options.MaxAge)*time.Second {
+		return true
+	}
+	return false
+}
+
+func (r *rotatingLogFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+
+	rotateErr := rotateLogBackups(r.path, r.options)
+
+	// Keep writing even if the backups could not be shifted, losing the old log is
+	// better than losing the new one.
+	if rotateErr != nil {
+		file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return errors.Join(rotateErr, err)
+		}
+		r.file = file
+		r.openedAt = time.Now()
+		return rotateErr
+	}
+
+	return r.open()
+}
+
+// rotateLogBackups moves path to path.1, shifting older backups up and dropping the
+// ones beyond MaxBackups. Compressed backups keep their .gz suffix.
+func rotateLogBackups(path string, options LogRotateOptions) error {
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) || (err == nil && info.Size() == 0) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	backups := options.MaxBackups
+	if backups <= 0 {
+		backups = 3
+	}
+
+	for _, ext := range []string{"", ".gz"} {
+		if err := os.Remove(logBackupName(path, backups) + ext); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	for i := backups - 1; i >= 1; i-- {
+		for _, ext := range []string{"", ".gz"} {
+			src := logBackupName(path, i) + ext
+			if _, err := os.Stat(src); err != nil {
+				continue
+			}
+			if err := os.Rename(src, logBackupName(path, i+1)+ext); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := os.Rename(path, logBackupName(path, 1)); err != nil {
+		return err
+	}
+
+	if options.Compress && backups >= 2 {
+		older := logBackupName(path, 2)
+		if _, err := os.Stat(older); err == nil {
+			if err := compressLogFile(older); err != nil {
+				log.Printf("Failed to compress log file %s: %v", older, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func logBackupName(path string, index int) string {
+	return path + "." + strconv.Itoa(index)
+}
+
+func compressLogFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dstPath := path + ".gz"
+	dst, err := os.OpenFile(dstPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	gzipWriter := gzip.NewWriter(dst)
+	_, err = io.Copy(gzipWriter, src)
+	if closeErr := gzipWriter.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(dstPath)
+		return err
+	}
+
+	src.Close()
+	return os.Remove(path)
+}
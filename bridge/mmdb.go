@@ -23,7 +23,10 @@ var (
 func (a *App) OpenMMDB(path string, id string) FlagResult {
 	log.Printf("OpenMMDB: %s -> %s", id, path)
 
-	dbPath := resolvePath(path)
+	dbPath, err := sandboxPath(path, sandboxRead)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
 
 	mu.Lock()
 	defer mu.Unlock()
@@ -49,7 +52,10 @@ func (a *App) OpenMMDB(path string, id string) FlagResult {
 func (a *App) CloseMMDB(path string, id string) FlagResult {
 	log.Printf("CloseMMDB: %s -> %s", id, path)
 
-	dbPath := resolvePath(path)
+	dbPath, err := sandboxPath(path, sandboxRead)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
 
 	mu.Lock()
 	defer mu.Unlock()
@@ -84,7 +90,10 @@ func (a *App) QueryMMDB(path string, ip string, dataType string) FlagResult {
 		return FlagResult{false, "Invalid IP address"}
 	}
 
-	dbPath := resolvePath(path)
+	dbPath, err := sandboxPath(path, sandboxRead)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
 
 	mu.RLock()
 	db, exists := mmdbMap[dbPath]
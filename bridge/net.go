@@ -66,6 +66,11 @@ func (a *App) Requests(method string, url string, headers map[string]string, bod
 			"headers": resp.Header,
 		})
 
+		batcher := newEventBatcher(a, options.Stream, options.Batch, func(dropped uint64) any {
+			return map[string]any{"type": "dropped", "count": dropped}
+		})
+		defer batcher.close()
+
 		scanner := bufio.NewScanner(resp.Body)
 		scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
 
@@ -101,7 +106,7 @@ func (a *App) Requests(method string, url string, headers map[string]string, bod
 			if retry != nil {
 				payload["retry"] = *retry
 			}
-			runtime.EventsEmit(a.Ctx, options.Stream, payload)
+			batcher.push(payload)
 		}
 
 		for scanner.Scan() {
@@ -145,6 +150,7 @@ func (a *App) Requests(method string, url string, headers map[string]string, bod
 		}
 
 		if err := scanner.Err(); err != nil {
+			batcher.close()
 			runtime.EventsEmit(a.Ctx, options.Stream, map[string]any{
 				"type":  "error",
 				"error": err.Error(),
@@ -153,6 +159,7 @@ func (a *App) Requests(method string, url string, headers map[string]string, bod
 		}
 
 		dispatch()
+		batcher.close()
 		runtime.EventsEmit(a.Ctx, options.Stream, map[string]any{"type": "done"})
 		return HTTPResult{true, resp.StatusCode, resp.Header, ""}
 	}
@@ -258,6 +265,11 @@ func (a *App) UdpRequest(address string, payload string, options NetOptions) Fla
 func (a *App) Download(method string, url string, path string, headers map[string]string, event string, options RequestOptions) HTTPResult {
 	log.Printf("Download: %s %s %s %v %s %v", method, url, path, headers, event, options)
 
+	path, err := sandboxPath(path, sandboxWrite)
+	if err != nil {
+		return HTTPResult{false, 500, nil, err.Error()}
+	}
+
 	client, ctx, cancel := withRequestOptionsClient(options)
 	defer cancel()
 
@@ -282,8 +294,6 @@ func (a *App) Download(method string, url string, path string, headers map[strin
 	}
 	defer resp.Body.Close()
 
-	path = resolvePath(path)
-
 	err = os.MkdirAll(filepath.Dir(path), os.ModePerm)
 	if err != nil {
 		return HTTPResult{false, 500, nil, err.Error()}
@@ -326,7 +336,10 @@ func (a *App) Download(method string, url string, path string, headers map[strin
 func (a *App) Upload(method string, url string, path string, headers map[string]string, event string, options RequestOptions) HTTPResult {
 	log.Printf("Upload: %s %s %s %v %s %v", method, url, path, headers, event, options)
 
-	path = resolvePath(path)
+	path, err := sandboxPath(path, sandboxRead)
+	if err != nil {
+		return HTTPResult{false, 500, nil, err.Error()}
+	}
 
 	file, err := os.Open(path)
 	if err != nil {
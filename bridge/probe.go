@@ -0,0 +1,210 @@
+package bridge
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const (
+	ProbeTCP  = "tcp"
+	ProbeHTTP = "http"
+	ProbeLog  = "log"
+)
+
+func validateProbe(probe ProbeOptions, liveness bool) error {
+	switch probe.Type {
+	case "":
+		return nil
+	case ProbeTCP:
+		if _, _, err := net.SplitHostPort(probe.Address); err != nil {
+			return fmt.Errorf("invalid address: %w", err)
+		}
+		return nil
+	case ProbeHTTP:
+		u, err := url.Parse(probe.Address)
+		if err != nil {
+			return fmt.Errorf("invalid address: %w", err)
+		}
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("invalid address: an http or https URL is required")
+		}
+		return nil
+	case ProbeLog:
+		if liveness {
+			return errors.New("log probes can only be used for readiness")
+		}
+		_, err := regexp.Compile(probe.Pattern)
+		return err
+	default:
+		return errors.New("Unsupported probe type: " + probe.Type)
+	}
+}
+
+func probeInterval(probe ProbeOptions, fallback time.Duration) time.Duration {
+	if probe.Interval <= 0 {
+		return fallback
+	}
+	return time.Duration(probe.Interval) * time.Millisecond
+}
+
+func probeTimeout(probe ProbeOptions, fallback time.Duration) time.Duration {
+	if probe.Timeout <= 0 {
+		return fallback
+	}
+	return time.Duration(probe.Timeout) * time.Second
+}
+
+// waitReady blocks until the readiness probe succeeds. When the probe times out or the
+// process exits first, the process is stopped and the last lines of output are attached
+// to the returned error.
+func (p *backgroundProcess) waitReady() error {
+	probe := p.options.ReadinessProbe
+	if probe.Type == "" {
+		return nil
+	}
+
+	timeout := probeTimeout(probe, 30*time.Second)
+	interval := probeInterval(probe, 500*time.Millisecond)
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := p.probe(probe, interval)
+		if err == nil {
+			p.mu.Lock()
+			p.ready = true
+			p.mu.Unlock()
+			return nil
+		}
+
+		select {
+		case <-p.done:
+			exitErr := p.wait()
+			if exitErr == nil {
+				exitErr = errors.New("exit status 0")
+			}
+			return fmt.Errorf("process exited before becoming ready: %v%s", exitErr, p.recentOutput())
+		case <-deadline.C:
+			if err := SendExitSignal(p.cmd.Process); err != nil {
+				log.Printf("SendExitSignal Err: %s", err.Error())
+			}
+			_ = waitForProcessExitWithTimeout(p.cmd.Process, 10)
+			_ = p.wait()
+			return fmt.Errorf("readiness probe timed out after %v: %v%s", timeout, err, p.recentOutput())
+		case <-p.logReady:
+		case <-ticker.C:
+		}
+	}
+}
+
+// watchLiveness probes the running process until it exits. onUnhealthy is called once
+// the failure threshold is reached.
+func (p *backgroundProcess) watchLiveness(onUnhealthy func()) {
+	probe := p.options.LivenessProbe
+	if probe.Type == "" {
+		return
+	}
+
+	interval := probeInterval(probe, 5*time.Second)
+	timeout := probeTimeout(probe, 5*time.Second)
+	threshold := probe.FailureThreshold
+	if threshold <= 0 {
+		threshold = 3
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		failures := 0
+
+		for {
+			select {
+			case <-p.done:
+				return
+			case <-ticker.C:
+			}
+
+			err := p.probe(probe, timeout)
+			if err == nil {
+				if failures >= threshold {
+					p.emitProbeEvent(map[string]any{"type": "liveness", "state": "healthy"})
+				}
+				failures = 0
+				continue
+			}
+
+			failures++
+			p.emitProbeEvent(map[string]any{
+				"type":     "liveness",
+				"state":    "failing",
+				"failures": failures,
+				"error":    err.Error(),
+			})
+
+			if failures == threshold {
+				p.emitProbeEvent(map[string]any{"type": "liveness", "state": "unhealthy", "failures": failures})
+				if onUnhealthy != nil {
+					onUnhealthy()
+				}
+			}
+		}
+	}()
+}
+
+func (p *backgroundProcess) probe(probe ProbeOptions, timeout time.Duration) error {
+	switch probe.Type {
+	case ProbeTCP:
+		conn, err := net.DialTimeout("tcp", probe.Address, timeout)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+
+	case ProbeHTTP:
+		client := &http.Client{Timeout: timeout}
+		resp, err := client.Get(probe.Address)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+
+		expected := probe.Status
+		if expected == 0 {
+			expected = http.StatusOK
+		}
+		if resp.StatusCode != expected {
+			return fmt.Errorf("unexpected status %d, expected %d", resp.StatusCode, expected)
+		}
+		return nil
+
+	case ProbeLog:
+		select {
+		case <-p.logReady:
+			return nil
+		default:
+			return fmt.Errorf("no output matched %q", probe.Pattern)
+		}
+	}
+
+	return errors.New("Unsupported probe type: " + probe.Type)
+}
+
+func (p *backgroundProcess) emitProbeEvent(payload map[string]any) {
+	if p.options.ProbeEvent == "" {
+		return
+	}
+	payload["pid"] = p.cmd.Process.Pid
+	runtime.EventsEmit(p.app.Ctx, p.options.ProbeEvent, payload)
+}
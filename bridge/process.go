@@ -0,0 +1,212 @@
+package bridge
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+type processNode struct {
+	Pid        int32          `json:"pid"`
+	Name       string         `json:"name"`
+	Cmdline    []string       `json:"cmdline"`
+	CreateTime int64          `json:"createTime"`
+	CPUPercent float64        `json:"cpuPercent"`
+	RSS        uint64         `json:"rss"`
+	Ports      []processPort  `json:"ports"`
+	Children   []*processNode `json:"children"`
+}
+
+type processPort struct {
+	Protocol string `json:"protocol"`
+	Address  string `json:"address"`
+	Port     uint32 `json:"port"`
+}
+
+func (a *App) ProcessTree(pid int32) FlagResult {
+	log.Printf("ProcessTree: %d", pid)
+
+	root, err := process.NewProcess(pid)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	children, err := processChildrenMap()
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	b, err := json.Marshal(buildProcessNode(root, children, map[int32]bool{}))
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	return FlagResult{true, string(b)}
+}
+
+func (a *App) KillProcessTree(pid int, options KillOptions) FlagResult {
+	log.Printf("KillProcessTree: %d %v", pid, options)
+
+	root, err := process.NewProcess(int32(pid))
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	children, err := processChildrenMap()
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	signals := options.Signals
+	if len(signals) == 0 {
+		signals = []string{"SIGINT", "SIGTERM", "SIGKILL"}
+	}
+	timeout := options.Timeout
+	if timeout <= 0 {
+		timeout = 5
+	}
+
+	// Remember when each process started so a recycled PID is never signalled.
+	// A recycled PID can also show up as its own ancestor, each one is visited once.
+	targets := map[int32]int64{}
+	var collect func(p *process.Process)
+	collect = func(p *process.Process) {
+		if _, ok := targets[p.Pid]; ok {
+			return
+		}
+		createTime, _ := p.CreateTime()
+		targets[p.Pid] = createTime
+		for _, child := range children[p.Pid] {
+			collect(child)
+		}
+	}
+	collect(root)
+
+	alive := func() []*os.Process {
+		var list []*os.Process
+		for pid, createTime := range targets {
+			p, err := process.NewProcess(pid)
+			if err != nil {
+				continue
+			}
+			if ct, err := p.CreateTime(); err == nil && ct != createTime {
+				continue
+			}
+			// Zombies are already dead, they only wait for their parent to reap them.
+			if status, err := p.Status(); err == nil && slices.Contains(status, process.Zombie) {
+				continue
+			}
+			proc, err := os.FindProcess(int(pid))
+			if err != nil {
+				continue
+			}
+			if ok, err := IsProcessAlive(proc); err == nil && ok {
+				list = append(list, proc)
+			}
+		}
+		return list
+	}
+
+	for _, signal := range signals {
+		procs := alive()
+		if len(procs) == 0 {
+			break
+		}
+
+		for _, proc := range procs {
+			if err := SignalProcess(proc, signal); err != nil {
+				log.Printf("SignalProcess %d %s Err: %s", proc.Pid, signal, err.Error())
+			}
+		}
+
+		deadline := time.Now().Add(time.Duration(timeout) * time.Second)
+		interval := 10 * time.Millisecond
+		for time.Now().Before(deadline) && len(alive()) > 0 {
+			time.Sleep(interval)
+			interval = min(interval*2, 500*time.Millisecond)
+		}
+	}
+
+	if procs := alive(); len(procs) > 0 {
+		pids := make([]string, 0, len(procs))
+		for _, proc := range procs {
+			pids = append(pids, fmt.Sprint(proc.Pid))
+		}
+		slices.Sort(pids)
+		return FlagResult{false, "processes still running: " + strings.Join(pids, ", ")}
+	}
+
+	return FlagResult{true, "Success"}
+}
+
+func processChildrenMap() (map[int32][]*process.Process, error) {
+	procs, err := process.Processes()
+	if err != nil {
+		return nil, err
+	}
+
+	children := make(map[int32][]*process.Process)
+	for _, p := range procs {
+		ppid, err := p.Ppid()
+		if err != nil || ppid == p.Pid {
+			continue
+		}
+		children[ppid] = append(children[ppid], p)
+	}
+
+	return children, nil
+}
+
+// buildProcessNode walks the tree below p. visited guards against cycles, a recycled
+// PID can show up as its own ancestor.
+func buildProcessNode(p *process.Process, children map[int32][]*process.Process, visited map[int32]bool) *processNode {
+	visited[p.Pid] = true
+
+	node := &processNode{
+		Pid:      p.Pid,
+		Ports:    []processPort{},
+		Children: []*processNode{},
+	}
+
+	node.Name, _ = p.Name()
+	node.Cmdline, _ = p.CmdlineSlice()
+	node.CreateTime, _ = p.CreateTime()
+	node.CPUPercent, _ = p.CPUPercent()
+	if memInfo, err := p.MemoryInfo(); err == nil && memInfo != nil {
+		node.RSS = memInfo.RSS
+	}
+
+	if conns, err := p.Connections(); err == nil {
+		for _, conn := range conns {
+			if conn.Family != syscall.AF_INET && conn.Family != syscall.AF_INET6 {
+				continue
+			}
+			// Listening TCP sockets and unconnected UDP sockets are the ones a process serves on.
+			listening := conn.Status == "LISTEN" || (conn.Type == syscall.SOCK_DGRAM && conn.Raddr.Port == 0)
+			if !listening {
+				continue
+			}
+			protocol := "tcp"
+			if conn.Type == syscall.SOCK_DGRAM {
+				protocol = "udp"
+			}
+			node.Ports = append(node.Ports, processPort{protocol, conn.Laddr.IP, conn.Laddr.Port})
+		}
+	}
+
+	for _, child := range children[p.Pid] {
+		if visited[child.Pid] {
+			continue
+		}
+		node.Children = append(node.Children, buildProcessNode(child, children, visited))
+	}
+
+	return node
+}
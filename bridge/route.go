@@ -0,0 +1,224 @@
+package bridge
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/netip"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Routes and policy rules are changed through fixed command templates filled with
+// validated values, the helper never runs a command line chosen by the client.
+
+var interfaceNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.:-]{0,14}$`)
+
+type helperRouteParams struct {
+	Action string       `json:"action"`
+	Route  RouteOptions `json:"route"`
+}
+
+type helperRouteRuleParams struct {
+	Action string           `json:"action"`
+	Rule   RouteRuleOptions `json:"rule"`
+}
+
+func (a *App) AddRoute(route RouteOptions) FlagResult {
+	log.Printf("AddRoute: %v", route)
+
+	return runRouteOperation("route", helperRouteParams{"add", route})
+}
+
+func (a *App) DeleteRoute(route RouteOptions) FlagResult {
+	log.Printf("DeleteRoute: %v", route)
+
+	return runRouteOperation("route", helperRouteParams{"delete", route})
+}
+
+// AddRouteRule adds a policy routing rule, linux only.
+func (a *App) AddRouteRule(rule RouteRuleOptions) FlagResult {
+	log.Printf("AddRouteRule: %v", rule)
+
+	return runRouteOperation("routeRule", helperRouteRuleParams{"add", rule})
+}
+
+func (a *App) DeleteRouteRule(rule RouteRuleOptions) FlagResult {
+	log.Printf("DeleteRouteRule: %v", rule)
+
+	return runRouteOperation("routeRule", helperRouteRuleParams{"delete", rule})
+}
+
+func runRouteOperation(op string, params any) FlagResult {
+	output, err := runPrivileged(op, params)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	return FlagResult{true, output}
+}
+
+func helperRoute(owner uint32, raw json.RawMessage, files []*os.File) (string, error) {
+	var params helperRouteParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return "", err
+	}
+
+	name, args, err := routeCommand(params.Action, params.Route)
+	if err != nil {
+		return "", err
+	}
+
+	return runNetworkCommand(name, args)
+}
+
+func helperRouteRule(owner uint32, raw json.RawMessage, files []*os.File) (string, error) {
+	var params helperRouteRuleParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return "", err
+	}
+
+	args, err := routeRuleCommand(params.Action, params.Rule)
+	if err != nil {
+		return "", err
+	}
+
+	return runNetworkCommand("ip", args)
+}
+
+func routeCommand(action string, route RouteOptions) (string, []string, error) {
+	if action != "add" && action != "delete" {
+		return "", nil, errors.New("invalid action: " + action)
+	}
+
+	dst, err := netip.ParsePrefix(route.Destination)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid destination: %w", err)
+	}
+	dst = dst.Masked()
+
+	var gateway netip.Addr
+	if route.Gateway != "" {
+		if gateway, err = netip.ParseAddr(route.Gateway); err != nil {
+			return "", nil, fmt.Errorf("invalid gateway: %w", err)
+		}
+		if gateway.Is4() != dst.Addr().Is4() {
+			return "", nil, errors.New("gateway and destination families differ")
+		}
+	}
+	if route.Device != "" && !interfaceNamePattern.MatchString(route.Device) {
+		return "", nil, errors.New("invalid device: " + route.Device)
+	}
+	if !gateway.IsValid() && route.Device == "" {
+		return "", nil, errors.New("a gateway or a device is required")
+	}
+	if route.Table < 0 || route.Metric < 0 {
+		return "", nil, errors.New("table and metric must not be negative")
+	}
+
+	switch Env.OS {
+	case "linux":
+		args := []string{"-4", "route", action, dst.String()}
+		if dst.Addr().Is6() {
+			args[0] = "-6"
+		}
+		if action == "delete" {
+			args[2] = "del"
+		}
+		if gateway.IsValid() {
+			args = append(args, "via", gateway.String())
+		}
+		if route.Device != "" {
+			args = append(args, "dev", route.Device)
+		}
+		if route.Table > 0 {
+			args = append(args, "table", strconv.Itoa(route.Table))
+		}
+		if route.Metric > 0 {
+			args = append(args, "metric", strconv.Itoa(route.Metric))
+		}
+		return "ip", args, nil
+
+	case "darwin":
+		if route.Table > 0 {
+			return "", nil, errors.New("routing tables are not supported on darwin")
+		}
+		args := []string{"-n", action}
+		if dst.Addr().Is6() {
+			args = append(args, "-inet6")
+		}
+		args = append(args, "-net", dst.String())
+		if gateway.IsValid() {
+			args = append(args, gateway.String())
+		} else {
+			args = append(args, "-interface", route.Device)
+		}
+		return "route", args, nil
+	}
+
+	return "", nil, errors.New("routes are not supported on " + Env.OS)
+}
+
+func routeRuleCommand(action string, rule RouteRuleOptions) ([]string, error) {
+	if Env.OS != "linux" {
+		return nil, errors.New("route rules are not supported on " + Env.OS)
+	}
+	if action != "add" && action != "delete" {
+		return nil, errors.New("invalid action: " + action)
+	}
+	if rule.Table <= 0 {
+		return nil, errors.New("a table is required")
+	}
+	if rule.Priority < 0 {
+		return nil, errors.New("priority must not be negative")
+	}
+
+	family := "-4"
+	if rule.IPv6 {
+		family = "-6"
+	}
+	args := []string{family, "rule", action}
+	if action == "delete" {
+		args[2] = "del"
+	}
+
+	for _, sel := range []struct{ key, value string }{{"from", rule.From}, {"to", rule.To}} {
+		if sel.value == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(sel.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", sel.key, err)
+		}
+		if prefix.Addr().Is6() != rule.IPv6 {
+			return nil, fmt.Errorf("%s does not match the rule family", sel.key)
+		}
+		args = append(args, sel.key, prefix.Masked().String())
+	}
+	if rule.Fwmark > 0 {
+		args = append(args, "fwmark", strconv.FormatUint(uint64(rule.Fwmark), 10))
+	}
+	if rule.Priority > 0 {
+		args = append(args, "priority", strconv.Itoa(rule.Priority))
+	}
+	args = append(args, "table", strconv.Itoa(rule.Table))
+
+	return args, nil
+}
+
+func runNetworkCommand(name string, args []string) (string, error) {
+	out, err := exec.Command(name, args...).CombinedOutput()
+	output := strings.TrimSpace(string(out))
+	if err != nil {
+		if output == "" {
+			output = err.Error()
+		}
+		return "", errors.New(output)
+	}
+
+	return output, nil
+}
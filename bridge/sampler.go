@@ -0,0 +1,261 @@
+package bridge
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+var samplerMap sync.Map
+
+type processSample struct {
+	Time      int64   `json:"time"`
+	CPU       float64 `json:"cpu"`
+	RSS       uint64  `json:"rss"`
+	Threads   int32   `json:"threads"`
+	FDs       int32   `json:"fds"`
+	BytesSent uint64  `json:"bytesSent"`
+	BytesRecv uint64  `json:"bytesRecv"`
+}
+
+type processSampler struct {
+	pid     int32
+	event   string
+	options SamplerOptions
+	proc    *process.Process
+
+	mu      sync.Mutex
+	samples []processSample
+	head    int
+	count   int
+	running bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func (a *App) StartSampler(pid int32, event string, options SamplerOptions) FlagResult {
+	log.Printf("StartSampler: %d %s %v", pid, event, options)
+
+	proc, err := process.NewProcess(pid)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	if options.Interval <= 0 {
+		options.Interval = 1000
+	}
+	if options.Capacity <= 0 {
+		options.Capacity = 300
+	}
+	if options.SummaryEvery <= 0 {
+		options.SummaryEvery = 10
+	}
+
+	s := &processSampler{
+		pid:     pid,
+		event:   event,
+		options: options,
+		proc:    proc,
+		samples: make([]processSample, options.Capacity),
+		running: true,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	if value, exists := samplerMap.LoadOrStore(pid, s); exists {
+		prev := value.(*processSampler)
+		select {
+		case <-prev.done:
+			if !samplerMap.CompareAndSwap(pid, prev, s) {
+				return FlagResult{false, "sampler already exists"}
+			}
+		default:
+			return FlagResult{false, "sampler already exists"}
+		}
+	}
+
+	go s.run(a)
+
+	return FlagResult{true, "Success"}
+}
+
+func (a *App) StopSampler(pid int32) FlagResult {
+	log.Printf("StopSampler: %d", pid)
+
+	value, ok := samplerMap.LoadAndDelete(pid)
+	if !ok {
+		return FlagResult{false, "sampler not found"}
+	}
+
+	s := value.(*processSampler)
+	select {
+	case <-s.done:
+	default:
+		close(s.stop)
+		<-s.done
+	}
+
+	return FlagResult{true, "Success"}
+}
+
+func (a *App) GetSamples(pid int32) FlagResult {
+	log.Printf("GetSamples: %d", pid)
+
+	value, ok := samplerMap.Load(pid)
+	if !ok {
+		return FlagResult{false, "sampler not found"}
+	}
+
+	s := value.(*processSampler)
+
+	s.mu.Lock()
+	result := map[string]any{
+		"pid":      s.pid,
+		"interval": s.options.Interval,
+		"running":  s.running,
+		"samples":  s.history(s.count),
+	}
+	s.mu.Unlock()
+
+	b, err := json.Marshal(result)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	return FlagResult{true, string(b)}
+}
+
+func (s *processSampler) run(a *App) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(time.Duration(s.options.Interval) * time.Millisecond)
+	defer ticker.Stop()
+
+	// The first CPU reading only establishes a baseline.
+	_, _ = s.proc.Percent(0)
+	taken := 0
+
+	for {
+		select {
+		case <-s.stop:
+			s.setRunning(false)
+			return
+		case <-ticker.C:
+		}
+
+		sample, err := s.sample()
+		if err != nil {
+			s.setRunning(false)
+			// The pid may be reused, the sampler of an exited process must not linger under it.
+			samplerMap.CompareAndDelete(s.pid, s)
+			if s.event != "" {
+				runtime.EventsEmit(a.Ctx, s.event, map[string]any{"pid": s.pid, "type": "exit", "error": err.Error()})
+			}
+			return
+		}
+
+		s.mu.Lock()
+		s.samples[(s.head+s.count)%len(s.samples)] = sample
+		if s.count < len(s.samples) {
+			s.count++
+		} else {
+			s.head = (s.head + 1) % len(s.samples)
+		}
+		taken++
+
+		var summary map[string]any
+		if s.event != "" && taken%s.options.SummaryEvery == 0 {
+			summary = summarizeSamples(s.history(min(s.options.SummaryEvery, s.count)))
+			summary["pid"] = s.pid
+			summary["type"] = "summary"
+		}
+		s.mu.Unlock()
+
+		if summary != nil {
+			runtime.EventsEmit(a.Ctx, s.event, summary)
+		}
+	}
+}
+
+func (s *processSampler) sample() (processSample, error) {
+	running, err := s.proc.IsRunning()
+	if err != nil {
+		return processSample{}, err
+	}
+	if !running {
+		return processSample{}, errors.New("process exited")
+	}
+
+	sample := processSample{Time: time.Now().UnixMilli()}
+
+	sample.CPU, _ = s.proc.Percent(0)
+	if total, ok := managedCgroupMemory(s.pid); ok {
+		sample.RSS = total
+	} else if memInfo, err := s.proc.MemoryInfo(); err == nil && memInfo != nil {
+		sample.RSS = memInfo.RSS
+	}
+	sample.Threads, _ = s.proc.NumThreads()
+	sample.FDs, _ = s.proc.NumFDs()
+
+	// Kernels do not account traffic per process, see processNetCounters.
+	if counters, err := processNetCounters(s.pid); err == nil && len(counters) > 0 {
+		sample.BytesSent = counters[0].BytesSent
+		sample.BytesRecv = counters[0].BytesRecv
+	}
+
+	return sample, nil
+}
+
+// history returns the newest n samples in chronological order. s.mu must be held.
+func (s *processSampler) history(n int) []processSample {
+	list := make([]processSample, 0, n)
+	for i := s.count - n; i < s.count; i++ {
+		list = append(list, s.samples[(s.head+i)%len(s.samples)])
+	}
+	return list
+}
+
+func (s *processSampler) setRunning(running bool) {
+	s.mu.Lock()
+	s.running = running
+	s.mu.Unlock()
+}
+
+func summarizeSamples(samples []processSample) map[string]any {
+	if len(samples) == 0 {
+		return map[string]any{"samples": 0}
+	}
+
+	first, last := samples[0], samples[len(samples)-1]
+
+	var cpuSum, cpuMax float64
+	var rssSum, rssMax uint64
+	for _, sample := range samples {
+		cpuSum += sample.CPU
+		cpuMax = max(cpuMax, sample.CPU)
+		rssSum += sample.RSS
+		rssMax = max(rssMax, sample.RSS)
+	}
+
+	summary := map[string]any{
+		"samples": len(samples),
+		"cpuAvg":  cpuSum / float64(len(samples)),
+		"cpuMax":  cpuMax,
+		"rssAvg":  rssSum / uint64(len(samples)),
+		"rssMax":  rssMax,
+		"last":    last,
+	}
+
+	if seconds := float64(last.Time-first.Time) / 1000; seconds > 0 && last.BytesSent >= first.BytesSent && last.BytesRecv >= first.BytesRecv {
+		summary["sendRate"] = float64(last.BytesSent-first.BytesSent) / seconds
+		summary["recvRate"] = float64(last.BytesRecv-first.BytesRecv) / seconds
+	}
+
+	return summary
+}
@@ -0,0 +1,14 @@
+//go:build linux
+
+package bridge
+
+import (
+	"fmt"
+
+	psnet "github.com/shirou/gopsutil/v3/net"
+)
+
+// processNetCounters reads the counters of the process's network namespace.
+func processNetCounters(pid int32) ([]psnet.IOCountersStat, error) {
+	return psnet.IOCountersByFile(false, fmt.Sprintf("/proc/%d/net/dev", pid))
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package bridge
+
+import (
+	psnet "github.com/shirou/gopsutil/v3/net"
+)
+
+// processNetCounters falls back to the system totals.
+func processNetCounters(pid int32) ([]psnet.IOCountersStat, error) {
+	return psnet.IOCounters(false)
+}
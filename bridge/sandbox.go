@@ -0,0 +1,368 @@
+package bridge
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Plugins address files as plugin://<token>/<path> to be checked against the
+// policy of their token. An absolute path follows the token, e.g. plugin://<token>//etc/hosts.
+// Paths without a token are checked against the configured sandbox. Only the app's
+// privileged calls, like updates replacing the executable next to data/, are exempt:
+// they address files as app://<key>/<path> with the key handed out by InitSandbox.
+const (
+	sandboxScheme    = "plugin://"
+	sandboxAppScheme = "app://"
+)
+
+var ErrPathDenied = errors.New("path denied by sandbox")
+
+// SandboxError reports a path rejected by the sandbox. It matches ErrPathDenied with errors.Is.
+type SandboxError struct {
+	Path   string
+	Reason string
+}
+
+func (e *SandboxError) Error() string {
+	return ErrPathDenied.Error() + ": " + e.Path + " (" + e.Reason + ")"
+}
+
+func (e *SandboxError) Unwrap() error {
+	return ErrPathDenied
+}
+
+type sandboxAccess int
+
+const (
+	sandboxRead sandboxAccess = iota
+	sandboxWrite
+	sandboxRemove // like sandboxWrite, but the roots themselves are protected
+)
+
+type sandboxPolicy struct {
+	name          string
+	roots         []string
+	allowAbsolute bool
+	readOnly      bool
+	commands      []string
+}
+
+// Files that configure the sandbox itself, only the app's privileged calls may change them.
+var sandboxProtected = []string{"data/user.yaml"}
+
+// Paths no policy may reach at all, whatever its roots.
+var sandboxHidden = []string{"data/.trash"}
+
+var (
+	sandboxTokens sync.Map
+
+	sandboxKey   string
+	sandboxKeyMu sync.Mutex
+)
+
+// InitSandbox hands out the key that tokens are minted with. It succeeds once per
+// run, the app claims it before any plugin is loaded.
+func (a *App) InitSandbox() FlagResult {
+	log.Printf("InitSandbox")
+
+	sandboxKeyMu.Lock()
+	defer sandboxKeyMu.Unlock()
+
+	if sandboxKey != "" {
+		return FlagResult{false, "sandbox already initialized"}
+	}
+
+	key, err := randomHex()
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+	sandboxKey = key
+
+	return FlagResult{true, key}
+}
+
+func (a *App) CreateSandboxToken(key string, options SandboxOptions) FlagResult {
+	log.Printf("CreateSandboxToken: %v", options)
+
+	if err := checkSandboxKey(key); err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	policy, err := newSandboxPolicy(options)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	token, err := randomHex()
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	sandboxTokens.Store(token, policy)
+
+	return FlagResult{true, token}
+}
+
+func (a *App) RevokeSandboxToken(key string, token string) FlagResult {
+	log.Printf("RevokeSandboxToken: %s", token)
+
+	if err := checkSandboxKey(key); err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	if _, ok := sandboxTokens.LoadAndDelete(token); !ok {
+		return FlagResult{false, "token not found"}
+	}
+
+	return FlagResult{true, "Success"}
+}
+
+// checkSandboxAdmin guards the calls that only the app may make once the sandbox is in
+// use, i.e. the ones without a path a token could be attached to.
+func checkSandboxAdmin(key string) error {
+	sandboxKeyMu.Lock()
+	initialized := sandboxKey != ""
+	sandboxKeyMu.Unlock()
+
+	if !initialized {
+		return nil
+	}
+	return checkSandboxKey(key)
+}
+
+func checkSandboxKey(key string) error {
+	sandboxKeyMu.Lock()
+	defer sandboxKeyMu.Unlock()
+
+	if sandboxKey == "" || subtle.ConstantTimeCompare([]byte(key), []byte(sandboxKey)) != 1 {
+		return errors.New("invalid sandbox key")
+	}
+	return nil
+}
+
+func randomHex() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// newSandboxPolicy builds the policy of a token. A token can only narrow the
+// configured sandbox: its roots have to lie within the configured ones.
+func newSandboxPolicy(options SandboxOptions) (*sandboxPolicy, error) {
+	policy := &sandboxPolicy{
+		name:          options.Name,
+		roots:         options.Roots,
+		allowAbsolute: options.AllowAbsolute,
+		readOnly:      options.ReadOnly,
+		commands:      options.Commands,
+	}
+	if len(policy.roots) == 0 {
+		policy.roots = sandboxRoots()
+	}
+
+	base := defaultSandboxPolicy()
+	base.name = "the sandbox config"
+	if policy.allowAbsolute && !base.allowAbsolute {
+		return nil, errors.New("absolute paths are not allowed by the sandbox config")
+	}
+	for _, root := range policy.roots {
+		if err := base.check(root, resolvePath(root), sandboxRead); err != nil {
+			return nil, err
+		}
+	}
+	for _, command := range policy.commands {
+		if !slices.Contains(Config.Sandbox.Commands, command) {
+			return nil, errors.New("command not allowed by the sandbox config: " + command)
+		}
+	}
+
+	return policy, nil
+}
+
+// defaultSandboxPolicy applies to calls without a token.
+func defaultSandboxPolicy() *sandboxPolicy {
+	return &sandboxPolicy{
+		name:          "default",
+		roots:         sandboxRoots(),
+		allowAbsolute: Config.Sandbox.AllowAbsolute,
+		commands:      Config.Sandbox.Commands,
+	}
+}
+
+func sandboxRoots() []string {
+	if len(Config.Sandbox.Roots) > 0 {
+		return Config.Sandbox.Roots
+	}
+	return []string{"data"}
+}
+
+// sandboxPolicyFor strips the token from path and returns the policy of the caller,
+// nil for the privileged calls of the app.
+func sandboxPolicyFor(path string) (*sandboxPolicy, string, error) {
+	if rest, ok := strings.CutPrefix(path, sandboxAppScheme); ok {
+		key, path, _ := strings.Cut(rest, "/")
+		if err := checkSandboxKey(key); err != nil {
+			return nil, "", &SandboxError{path, err.Error()}
+		}
+		return nil, path, nil
+	}
+
+	rest, ok := strings.CutPrefix(path, sandboxScheme)
+	if !ok {
+		return defaultSandboxPolicy(), path, nil
+	}
+
+	token, path, _ := strings.Cut(rest, "/")
+	value, ok := sandboxTokens.Load(token)
+	if !ok {
+		return nil, "", &SandboxError{path, "invalid token"}
+	}
+
+	return value.(*sandboxPolicy), path, nil
+}
+
+// sandboxPath resolves path like resolvePath after checking it against the policy of its caller.
+func sandboxPath(path string, access sandboxAccess) (string, error) {
+	_, fullPath, err := sandboxResolve(path, access)
+	return fullPath, err
+}
+
+// sandboxResolve is sandboxPath that also returns the policy, for callers walking below path.
+func sandboxResolve(path string, access sandboxAccess) (*sandboxPolicy, string, error) {
+	policy, path, err := sandboxPolicyFor(path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	fullPath := resolvePath(path)
+	if err := policy.check(path, fullPath, access); err != nil {
+		return nil, "", err
+	}
+
+	return policy, fullPath, nil
+}
+
+// hides reports whether a path met while walking a tree is out of reach of the policy.
+func (p *sandboxPolicy) hides(fullPath string) bool {
+	if p == nil {
+		return false
+	}
+
+	fullPath = filepath.ToSlash(fullPath)
+	for _, hidden := range sandboxHidden {
+		if pathWithin(resolvePath(hidden), fullPath) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *sandboxPolicy) check(path, fullPath string, access sandboxAccess) error {
+	if p == nil {
+		return nil
+	}
+
+	if access != sandboxRead && p.readOnly {
+		return &SandboxError{path, "read-only access for " + p.name}
+	}
+
+	realPath := evalExistingSymlinks(fullPath)
+
+	for _, hidden := range sandboxHidden {
+		hiddenPath := resolvePath(hidden)
+		if pathWithin(hiddenPath, fullPath) || pathWithin(hiddenPath, realPath) || (access != sandboxRead && pathWithin(fullPath, hiddenPath)) {
+			return &SandboxError{path, hidden + " is not accessible"}
+		}
+	}
+
+	if access != sandboxRead {
+		for _, protected := range sandboxProtected {
+			protectedPath := resolvePath(protected)
+			// Neither the file itself, a link to it, nor a directory holding it.
+			if pathWithin(fullPath, protectedPath) || pathWithin(protectedPath, fullPath) || pathWithin(protectedPath, realPath) {
+				return &SandboxError{path, protected + " is protected"}
+			}
+		}
+	}
+
+	for _, root := range p.roots {
+		rootPath := resolvePath(root)
+		if !pathWithin(rootPath, fullPath) {
+			continue
+		}
+		if access == sandboxRemove && fullPath == rootPath {
+			return &SandboxError{path, "cannot remove sandbox root " + root}
+		}
+		// A link inside the root must not lead out of it.
+		if !pathWithin(evalExistingSymlinks(rootPath), realPath) {
+			return &SandboxError{path, "symlink escapes " + root}
+		}
+		return nil
+	}
+
+	if p.allowAbsolute && filepath.IsAbs(path) {
+		return nil
+	}
+
+	return &SandboxError{path, "outside of the allowed roots of " + p.name}
+}
+
+// allowsCommand reports whether name may be looked up in PATH. Only bare names can be
+// allowed, anything else would be resolved against the working directory of the app.
+func (p *sandboxPolicy) allowsCommand(name string) bool {
+	if p == nil {
+		return true
+	}
+	return !strings.ContainsAny(name, `/\`) && slices.Contains(p.commands, name)
+}
+
+// pathWithin reports whether path is root or lies below it.
+func pathWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	return rel != ".." && !strings.HasPrefix(rel, "../")
+}
+
+// evalExistingSymlinks resolves the symlinks of the part of path that exists, including
+// dangling links that a write would create their target through, and keeps the rest as is.
+func evalExistingSymlinks(path string) string {
+	rest := ""
+	for hops := 0; hops < 255; {
+		if real, err := filepath.EvalSymlinks(path); err == nil {
+			return filepath.ToSlash(filepath.Join(real, rest))
+		}
+
+		if stat, err := os.Lstat(path); err == nil && stat.Mode()&os.ModeSymlink != 0 {
+			if target, err := os.Readlink(path); err == nil {
+				if !filepath.IsAbs(target) {
+					target = filepath.Join(filepath.Dir(path), target)
+				}
+				path = target
+				hops++
+				continue
+			}
+		}
+
+		parent := filepath.Dir(path)
+		if parent == path {
+			break
+		}
+		rest = filepath.Join(filepath.Base(path), rest)
+		path = parent
+	}
+
+	return filepath.ToSlash(filepath.Join(path, rest))
+}
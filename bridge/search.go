@@ -0,0 +1,230 @@
+package bridge
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// Matches in longer lines are reported with a window of the line around them.
+const maxSearchLineLength = 1000
+
+type searchMatch struct {
+	Path   string `json:"path"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"` // byte offset of the match in the line
+	Offset int    `json:"offset"` // byte offset of text in the line
+	Text   string `json:"text"`
+}
+
+type searchResult struct {
+	Files     int  `json:"files"`
+	Matches   int  `json:"matches"`
+	Truncated bool `json:"truncated"`
+	Canceled  bool `json:"canceled"`
+}
+
+// SearchFiles greps the files below root and emits the matches of each file to
+// options.Event as they are found. It returns a summary once the walk is done.
+func (a *App) SearchFiles(root string, pattern string, options SearchOptions) FlagResult {
+	log.Printf("SearchFiles: %s %q %v", root, pattern, options)
+
+	policy, rootPath, err := sandboxResolve(root, sandboxRead)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	if pattern == "" {
+		return FlagResult{false, "empty pattern"}
+	}
+	expr := pattern
+	if !options.Regex {
+		expr = regexp.QuoteMeta(pattern)
+	}
+	if options.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	if options.MaxFileSize <= 0 {
+		options.MaxFileSize = 10 * 1024 * 1024
+	}
+	if options.MaxResults <= 0 {
+		options.MaxResults = 1000
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if options.CancelId != "" {
+		runtime.EventsOn(a.Ctx, options.CancelId, func(data ...any) {
+			log.Printf("SearchFiles Canceled: %v %q", root, pattern)
+			cancel()
+		})
+		defer runtime.EventsOff(a.Ctx, options.CancelId)
+	}
+
+	result := searchResult{}
+	errLimit := errors.New("result limit reached")
+
+	err = filepath.WalkDir(rootPath, func(path string, d fs.DirEntry, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			if path == rootPath {
+				return err
+			}
+			// Unreadable subdirectories should not stop the search.
+			return nil
+		}
+
+		rel, err := filepath.Rel(rootPath, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if path != rootPath && (matchPathGlob(options.Exclude, rel) || policy.hides(path)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// Links are skipped, they could lead out of the searched tree.
+		if !d.Type().IsRegular() || matchPathGlob(options.Exclude, rel) {
+			return nil
+		}
+		if len(options.Include) > 0 && !matchPathGlob(options.Include, rel) {
+			return nil
+		}
+		if info, err := d.Info(); err != nil || info.Size() > options.MaxFileSize {
+			return nil
+		}
+
+		matches, err := searchFile(ctx, path, re, options.MaxResults-result.Matches)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Printf("SearchFiles %s: %v", path, err)
+			return nil
+		}
+
+		result.Files++
+		if len(matches) == 0 {
+			return nil
+		}
+
+		for i := range matches {
+			matches[i].Path = rel
+		}
+		result.Matches += len(matches)
+		if options.Event != "" {
+			runtime.EventsEmit(a.Ctx, options.Event, matches)
+		}
+
+		if result.Matches >= options.MaxResults {
+			return errLimit
+		}
+		return nil
+	})
+
+	switch {
+	case errors.Is(err, errLimit):
+		result.Truncated = true
+	case errors.Is(err, context.Canceled):
+		result.Canceled = true
+	case err != nil:
+		return FlagResult{false, err.Error()}
+	}
+
+	b, err := json.Marshal(result)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	return FlagResult{true, string(b)}
+}
+
+// searchFile returns up to limit matching lines of a text file. Binary files have no matches.
+func searchFile(ctx context.Context, path string, re *regexp.Regexp, limit int) ([]searchMatch, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	head, err := reader.Peek(512)
+	if err != nil && err != io.EOF && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, err
+	}
+	if bytes.IndexByte(head, 0) >= 0 {
+		return nil, nil
+	}
+
+	matches := []searchMatch{}
+	for line := 1; ; line++ {
+		if line%1000 == 0 && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		// ReadBytes grows with the line, the size of the whole file is capped by the caller.
+		text, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if len(text) == 0 && err == io.EOF {
+			break
+		}
+
+		text = bytes.TrimRight(text, "\r\n")
+		if loc := re.FindIndex(text); loc != nil {
+			start, end := searchWindow(len(text), loc)
+			matches = append(matches, searchMatch{
+				Line:   line,
+				Column: loc[0],
+				Offset: start,
+				Text:   strings.ToValidUTF8(string(text[start:end]), ""),
+			})
+			if len(matches) >= limit {
+				break
+			}
+		}
+
+		if err == io.EOF {
+			break
+		}
+	}
+
+	return matches, nil
+}
+
+// searchWindow returns the part of a line of length n shown for the match at loc,
+// centered on the match when the line is too long.
+func searchWindow(n int, loc []int) (int, int) {
+	if n <= maxSearchLineLength {
+		return 0, n
+	}
+
+	start := max(loc[0]-max(maxSearchLineLength-(loc[1]-loc[0]), 0)/2, 0)
+	end := min(start+maxSearchLineLength, n)
+	start = max(end-maxSearchLineLength, 0)
+
+	return start, end
+}
@@ -50,7 +50,10 @@ func (a *App) StartServer(address string, serverID string, options ServerOptions
 	mux := http.NewServeMux()
 
 	if options.StaticPath != "" && options.StaticRoute != "" {
-		static := resolvePath(options.StaticPath)
+		static, err := sandboxPath(options.StaticPath, sandboxRead)
+		if err != nil {
+			return FlagResult{false, err.Error()}
+		}
 		fs := http.StripPrefix(options.StaticRoute, http.FileServer(http.Dir(static)))
 
 		mux.HandleFunc(options.StaticRoute, func(w http.ResponseWriter, r *http.Request) {
@@ -59,7 +62,10 @@ func (a *App) StartServer(address string, serverID string, options ServerOptions
 	}
 
 	if options.UploadPath != "" && options.UploadRoute != "" {
-		uploadPath := resolvePath(options.UploadPath)
+		uploadPath, err := sandboxPath(options.UploadPath, sandboxWrite)
+		if err != nil {
+			return FlagResult{false, err.Error()}
+		}
 		if err := os.MkdirAll(uploadPath, os.ModePerm); err != nil {
 			return FlagResult{false, "Failed to create upload directory: " + err.Error()}
 		}
@@ -76,7 +82,15 @@ func (a *App) StartServer(address string, serverID string, options ServerOptions
 
 	var listener net.Listener
 	if options.Cert != "" && options.Key != "" {
-		cert, err := tls.LoadX509KeyPair(resolvePath(options.Cert), resolvePath(options.Key))
+		certPath, err := sandboxPath(options.Cert, sandboxRead)
+		if err != nil {
+			return FlagResult{false, err.Error()}
+		}
+		keyPath, err := sandboxPath(options.Key, sandboxRead)
+		if err != nil {
+			return FlagResult{false, err.Error()}
+		}
+		cert, err := tls.LoadX509KeyPair(certPath, keyPath)
 		if err != nil {
 			return FlagResult{false, "Failed to load TLS cert: " + err.Error()}
 		}
@@ -0,0 +1,312 @@
+package bridge
+
+import (
+	"encoding/base64"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+var sessionMap sync.Map
+
+type sessionEntry struct {
+	cmd    *exec.Cmd
+	pty    *os.File
+	mu     sync.Mutex
+	stdin  io.WriteCloser
+	closed bool
+	// writeMu keeps writes in order without holding mu while one blocks.
+	writeMu sync.Mutex
+}
+
+func (a *App) StartSession(id string, path string, args []string, outEvent string, endEvent string, options ExecOptions, session SessionOptions) FlagResult {
+	log.Printf("StartSession: %s %s %s %s %s %v %v", id, path, args, outEvent, endEvent, options, session)
+
+	entry := &sessionEntry{}
+	if _, exists := sessionMap.LoadOrStore(id, entry); exists {
+		return FlagResult{false, "session already exists"}
+	}
+	started := false
+	defer func() {
+		if !started {
+			sessionMap.Delete(id)
+		}
+	}()
+
+	cmd, err := newExecCommand(path, args, options)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	var output io.Reader
+	var stdin io.WriteCloser
+	var ptyFile *os.File
+
+	if session.Pty {
+		hasTerm := slices.ContainsFunc(cmd.Env, func(env string) bool { return strings.HasPrefix(env, "TERM=") })
+		if !hasTerm {
+			cmd.Env = append(cmd.Env, "TERM=xterm-256color")
+		}
+
+		rows, cols := sessionSize(session)
+		f, err := startPty(cmd, rows, cols)
+		if err != nil {
+			return FlagResult{false, err.Error()}
+		}
+		ptyFile = f
+		stdin = f
+		output = f
+	} else {
+		stdinPipe, err := cmd.StdinPipe()
+		if err != nil {
+			return FlagResult{false, err.Error()}
+		}
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return FlagResult{false, err.Error()}
+		}
+		cmd.Stderr = cmd.Stdout
+
+		if err := cmd.Start(); err != nil {
+			return FlagResult{false, err.Error()}
+		}
+		stdin = stdinPipe
+		output = stdout
+	}
+
+	entry.mu.Lock()
+	entry.cmd = cmd
+	entry.pty = ptyFile
+	entry.stdin = stdin
+	entry.mu.Unlock()
+
+	outputDone := make(chan struct{})
+	go func() {
+		defer close(outputDone)
+
+		var batcher *eventBatcher
+		if outEvent != "" {
+			batcher = newEventBatcher(a, outEvent, options.Batch, nil)
+			defer batcher.close()
+		}
+
+		buf := make([]byte, 32*1024)
+		// Bytes of a character split across reads, kept for the next chunk.
+		var pending []byte
+		for {
+			n, err := output.Read(buf)
+			if n > 0 && batcher != nil {
+				if session.Mode == Binary {
+					batcher.push(base64.StdEncoding.EncodeToString(buf[:n]))
+				} else {
+					chunk := append(pending, buf[:n]...)
+					keep := commandOutputTail(chunk)
+					pending = slices.Clone(chunk[len(chunk)-keep:])
+					if len(chunk) > keep {
+						batcher.push(DecodeCommandOutput(chunk[:len(chunk)-keep]))
+					}
+				}
+			}
+			if err != nil {
+				if len(pending) > 0 {
+					batcher.push(DecodeCommandOutput(pending))
+				}
+				// A PTY reports EIO once the child side has been closed.
+				return
+			}
+		}
+	}()
+
+	go func() {
+		<-outputDone
+		err := cmd.Wait()
+
+		if ptyFile != nil {
+			ptyFile.Close()
+		}
+		sessionMap.CompareAndDelete(id, entry)
+
+		if endEvent != "" {
+			if err != nil {
+				runtime.EventsEmit(a.Ctx, endEvent, err.Error())
+				return
+			}
+			runtime.EventsEmit(a.Ctx, endEvent)
+		}
+	}()
+
+	started = true
+	return FlagResult{true, strconv.Itoa(cmd.Process.Pid)}
+}
+
+func (a *App) WriteSession(id string, content string, options IOOptions) FlagResult {
+	log.Printf("WriteSession [%s]: %s", options.Mode, id)
+
+	entry, err := loadSession(id)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	var data []byte
+
+	switch options.Mode {
+	case Text:
+		data = []byte(content)
+	case Binary:
+		data, err = base64.StdEncoding.DecodeString(content)
+		if err != nil {
+			return FlagResult{false, err.Error()}
+		}
+	default:
+		return FlagResult{false, "Unsupported IO mode: " + options.Mode}
+	}
+
+	entry.writeMu.Lock()
+	defer entry.writeMu.Unlock()
+
+	entry.mu.Lock()
+	closed, stdin := entry.closed, entry.stdin
+	entry.mu.Unlock()
+
+	if closed {
+		return FlagResult{false, "session stdin is closed"}
+	}
+
+	if _, err := stdin.Write(data); err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	return FlagResult{true, "Success"}
+}
+
+func (a *App) ResizeSession(id string, rows int, cols int) FlagResult {
+	log.Printf("ResizeSession: %s %d %d", id, rows, cols)
+
+	entry, err := loadSession(id)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	if entry.pty == nil {
+		return FlagResult{false, "session has no terminal"}
+	}
+
+	if rows <= 0 || cols <= 0 || rows > 0xffff || cols > 0xffff {
+		return FlagResult{false, "invalid terminal size"}
+	}
+
+	if err := resizePty(entry.pty, uint16(rows), uint16(cols)); err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	return FlagResult{true, "Success"}
+}
+
+func (a *App) CloseSessionStdin(id string) FlagResult {
+	log.Printf("CloseSessionStdin: %s", id)
+
+	entry, err := loadSession(id)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	entry.mu.Lock()
+	if entry.closed {
+		entry.mu.Unlock()
+		return FlagResult{true, "Success"}
+	}
+	entry.closed = true
+	entry.mu.Unlock()
+
+	// Closing the master side of a terminal would hang up the whole session,
+	// so send end-of-transmission like a user pressing Ctrl+D instead, after
+	// the writes already queued. Closing a pipe also unblocks a pending write.
+	if entry.pty != nil {
+		entry.writeMu.Lock()
+		_, err = entry.pty.Write([]byte{0x04})
+		entry.writeMu.Unlock()
+	} else {
+		err = entry.stdin.Close()
+	}
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	return FlagResult{true, "Success"}
+}
+
+func (a *App) KillSession(id string, timeout int) FlagResult {
+	log.Printf("KillSession: %s %d", id, timeout)
+
+	entry, err := loadSession(id)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	if err := SendExitSignal(entry.cmd.Process); err != nil {
+		log.Printf("SendExitSignal Err: %s", err.Error())
+	}
+
+	if err := waitForProcessExitWithTimeout(entry.cmd.Process, timeout); err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	return FlagResult{true, "Success"}
+}
+
+func (a *App) ListSession() FlagResult {
+	log.Printf("ListSession")
+
+	var sessions []string
+
+	sessionMap.Range(func(key, value any) bool {
+		sessionID, ok := key.(string)
+		if _, err := loadSession(sessionID); ok && err == nil {
+			sessions = append(sessions, sessionID)
+		}
+		return true
+	})
+
+	return FlagResult{true, strings.Join(sessions, "|")}
+}
+
+func loadSession(id string) (*sessionEntry, error) {
+	value, ok := sessionMap.Load(id)
+	if !ok {
+		return nil, errors.New("session not found")
+	}
+
+	entry, ok := value.(*sessionEntry)
+	if !ok {
+		return nil, errors.New("invalid session type")
+	}
+
+	entry.mu.Lock()
+	started := entry.stdin != nil
+	entry.mu.Unlock()
+
+	if !started {
+		return nil, errors.New("session not found")
+	}
+
+	return entry, nil
+}
+
+func sessionSize(session SessionOptions) (uint16, uint16) {
+	rows, cols := uint16(24), uint16(80)
+	if session.Rows > 0 && session.Rows <= 0xffff {
+		rows = uint16(session.Rows)
+	}
+	if session.Cols > 0 && session.Cols <= 0xffff {
+		cols = uint16(session.Cols)
+	}
+	return rows, cols
+}
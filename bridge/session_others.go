@@ -0,0 +1,18 @@
+//go:build !windows
+
+package bridge
+
+import (
+	"os"
+	"os/exec"
+
+	"github.com/creack/pty"
+)
+
+func startPty(cmd *exec.Cmd, rows uint16, cols uint16) (*os.File, error) {
+	return pty.StartWithSize(cmd, &pty.Winsize{Rows: rows, Cols: cols})
+}
+
+func resizePty(f *os.File, rows uint16, cols uint16) error {
+	return pty.Setsize(f, &pty.Winsize{Rows: rows, Cols: cols})
+}
@@ -0,0 +1,17 @@
+//go:build windows
+
+package bridge
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+func startPty(cmd *exec.Cmd, rows uint16, cols uint16) (*os.File, error) {
+	return nil, errors.New("pseudo-terminal sessions are not supported on windows")
+}
+
+func resizePty(f *os.File, rows uint16, cols uint16) error {
+	return errors.New("pseudo-terminal sessions are not supported on windows")
+}
@@ -0,0 +1,328 @@
+package bridge
+
+import (
+	"encoding/json"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const (
+	RestartNever     = "never"
+	RestartOnFailure = "on-failure"
+	RestartAlways    = "always"
+)
+
+const (
+	StateStarting   = "starting"
+	StateRunning    = "running"
+	StateExited     = "exited"
+	StateBackingOff = "backing-off"
+	StateGaveUp     = "gave-up"
+	StateStopped    = "stopped"
+)
+
+var supervisorMap sync.Map
+
+type supervisorStatus struct {
+	Id        string `json:"id"`
+	State     string `json:"state"`
+	Pid       int    `json:"pid"`
+	Restarts  int    `json:"restarts"`
+	ExitCode  int    `json:"exitCode"`
+	StartedAt int64  `json:"startedAt"`
+}
+
+type supervisedProcess struct {
+	app        *App
+	path       string
+	args       []string
+	outEvent   string
+	stateEvent string
+	options    ExecOptions
+	policy     SupervisorOptions
+
+	mu       sync.Mutex
+	status   supervisorStatus
+	proc     *backgroundProcess
+	stopping bool
+	stop     chan struct{}
+	stopped  chan struct{}
+}
+
+func (a *App) StartSupervised(id string, path string, args []string, outEvent string, stateEvent string, options ExecOptions, policy SupervisorOptions) FlagResult {
+	log.Printf("StartSupervised: %s %s %s %s %s %v %v", id, path, args, outEvent, stateEvent, options, policy)
+
+	switch policy.RestartPolicy {
+	case "":
+		policy.RestartPolicy = RestartOnFailure
+	case RestartNever, RestartOnFailure, RestartAlways:
+	default:
+		return FlagResult{false, "Unsupported restart policy: " + policy.RestartPolicy}
+	}
+
+	// Options are otherwise only looked at when the process starts, a mistake would
+	// then be retried until MaxRestarts is used up.
+	if err := validateExecOptions(options); err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	if policy.MaxRestarts <= 0 {
+		policy.MaxRestarts = 5
+	}
+	if policy.RestartWindow <= 0 {
+		policy.RestartWindow = 60
+	}
+	if policy.BackoffMin <= 0 {
+		policy.BackoffMin = 500
+	}
+	if policy.BackoffMax < policy.BackoffMin {
+		policy.BackoffMax = max(policy.BackoffMin, 30*1000)
+	}
+	if policy.StopTimeout <= 0 {
+		policy.StopTimeout = 10
+	}
+
+	sp := &supervisedProcess{
+		app:        a,
+		path:       path,
+		args:       args,
+		outEvent:   outEvent,
+		stateEvent: stateEvent,
+		options:    options,
+		policy:     policy,
+		status:     supervisorStatus{Id: id, State: StateStarting},
+		stop:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+
+	if value, exists := supervisorMap.LoadOrStore(id, sp); exists {
+		prev := value.(*supervisedProcess)
+		select {
+		case <-prev.stopped:
+			// The previous instance has already finished, so its slot can be reused.
+			if !supervisorMap.CompareAndSwap(id, prev, sp) {
+				return FlagResult{false, "supervised process already exists"}
+			}
+		default:
+			return FlagResult{false, "supervised process already exists"}
+		}
+	}
+
+	go sp.run()
+
+	return FlagResult{true, "Success"}
+}
+
+func (a *App) StopSupervised(id string) FlagResult {
+	log.Printf("StopSupervised: %s", id)
+
+	value, ok := supervisorMap.Load(id)
+	if !ok {
+		return FlagResult{false, "supervised process not found"}
+	}
+	sp := value.(*supervisedProcess)
+
+	sp.mu.Lock()
+	if !sp.stopping {
+		sp.stopping = true
+		close(sp.stop)
+	}
+	proc := sp.proc
+	sp.mu.Unlock()
+
+	if proc != nil {
+		if err := sp.terminate(proc); err != nil {
+			return FlagResult{false, err.Error()}
+		}
+	}
+
+	select {
+	case <-sp.stopped:
+	case <-time.After(time.Duration(sp.policy.StopTimeout+5) * time.Second):
+		return FlagResult{false, "timed out waiting for supervised process to stop"}
+	}
+
+	supervisorMap.CompareAndDelete(id, sp)
+
+	return FlagResult{true, "Success"}
+}
+
+func (a *App) ListSupervised() FlagResult {
+	log.Printf("ListSupervised")
+
+	list := []supervisorStatus{}
+
+	supervisorMap.Range(func(key, value any) bool {
+		sp := value.(*supervisedProcess)
+		sp.mu.Lock()
+		list = append(list, sp.status)
+		sp.mu.Unlock()
+		return true
+	})
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Id < list[j].Id })
+
+	b, err := json.Marshal(list)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	return FlagResult{true, string(b)}
+}
+
+func (sp *supervisedProcess) run() {
+	defer close(sp.stopped)
+
+	var recentRestarts []time.Time
+	attempt := 0
+	window := time.Duration(sp.policy.RestartWindow) * time.Second
+
+	for {
+		sp.setState(StateStarting, nil)
+
+		startedAt := time.Now()
+		exitCode := -1
+
+		proc, err := startBackgroundProcess(sp.app, sp.path, sp.args, sp.outEvent, sp.options)
+		unhealthy := false
+		if err == nil {
+			sp.mu.Lock()
+			stopping := sp.stopping
+			sp.proc = proc
+			sp.status.Pid = proc.cmd.Process.Pid
+			sp.status.StartedAt = startedAt.UnixMilli()
+			sp.mu.Unlock()
+
+			if stopping {
+				_ = sp.terminate(proc)
+			}
+
+			if err = proc.waitReady(); err == nil {
+				sp.setState(StateRunning, map[string]any{"pid": proc.cmd.Process.Pid})
+
+				proc.watchLiveness(func() {
+					if sp.options.LivenessProbe.Restart {
+						proc.unhealthy.Store(true)
+						_ = sp.terminate(proc)
+					}
+				})
+
+				err = proc.wait()
+			}
+
+			if proc.cmd.ProcessState != nil {
+				exitCode = proc.cmd.ProcessState.ExitCode()
+			}
+			unhealthy = proc.unhealthy.Load()
+
+			sp.mu.Lock()
+			sp.proc = nil
+			sp.status.Pid = 0
+			sp.status.ExitCode = exitCode
+			sp.mu.Unlock()
+		}
+
+		exited := map[string]any{"code": exitCode}
+		if err != nil {
+			exited["error"] = err.Error()
+		}
+		if unhealthy {
+			exited["unhealthy"] = true
+		}
+		sp.setState(StateExited, exited)
+
+		if sp.isStopping() {
+			sp.setState(StateStopped, nil)
+			return
+		}
+
+		failed := err != nil || exitCode != 0 || unhealthy
+		if sp.policy.RestartPolicy == RestartNever || (sp.policy.RestartPolicy == RestartOnFailure && !failed) {
+			return
+		}
+
+		// A process that stayed up for a whole window is considered healthy again.
+		now := time.Now()
+		if now.Sub(startedAt) >= window {
+			attempt = 0
+		}
+		recentRestarts = append(recentRestarts, now)
+		for len(recentRestarts) > 0 && now.Sub(recentRestarts[0]) > window {
+			recentRestarts = recentRestarts[1:]
+		}
+		if len(recentRestarts) > sp.policy.MaxRestarts {
+			sp.setState(StateGaveUp, nil)
+			return
+		}
+
+		delay := supervisorBackoff(sp.policy, attempt)
+		attempt++
+
+		sp.setState(StateBackingOff, map[string]any{"delay": delay.Milliseconds()})
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-sp.stop:
+			timer.Stop()
+			sp.setState(StateStopped, nil)
+			return
+		case <-timer.C:
+		}
+
+		sp.mu.Lock()
+		sp.status.Restarts++
+		sp.mu.Unlock()
+	}
+}
+
+func (sp *supervisedProcess) terminate(proc *backgroundProcess) error {
+	if err := SendExitSignal(proc.cmd.Process); err != nil {
+		log.Printf("SendExitSignal Err: %s", err.Error())
+	}
+	return waitForProcessExitWithTimeout(proc.cmd.Process, sp.policy.StopTimeout)
+}
+
+func (sp *supervisedProcess) isStopping() bool {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	return sp.stopping
+}
+
+func (sp *supervisedProcess) setState(state string, fields map[string]any) {
+	sp.mu.Lock()
+	sp.status.State = state
+	status := sp.status
+	sp.mu.Unlock()
+
+	if sp.stateEvent == "" {
+		return
+	}
+
+	payload := map[string]any{
+		"id":       status.Id,
+		"state":    state,
+		"restarts": status.Restarts,
+	}
+	for key, value := range fields {
+		payload[key] = value
+	}
+	runtime.EventsEmit(sp.app.Ctx, sp.stateEvent, payload)
+}
+
+func supervisorBackoff(policy SupervisorOptions, attempt int) time.Duration {
+	delay := time.Duration(policy.BackoffMin) * time.Millisecond
+	maxDelay := time.Duration(policy.BackoffMax) * time.Millisecond
+
+	for range attempt {
+		delay *= 2
+		if delay >= maxDelay {
+			return maxDelay
+		}
+	}
+
+	return min(delay, maxDelay)
+}
@@ -73,12 +73,42 @@ func (a *App) SetSystemDNS(servers string, services []string) FlagResult {
 		return FlagResult{true, "Success"}
 	}
 
+	// Only ask for the helper when the system refused us, most desktops let users change DNS.
+	if err != nil && !Env.IsPrivileged && isPermissionError(err) {
+		log.Printf("SetSystemDNS: retrying through the privileged helper: %v", err)
+		_, err = runPrivileged("setDNS", helperDNSParams{servers, services})
+	}
+
 	if err != nil {
 		return FlagResult{false, err.Error()}
 	}
 	return FlagResult{true, "Success"}
 }
 
+// isPermissionError recognizes the ways nmcli and networksetup report missing privileges.
+func isPermissionError(err error) bool {
+	if errors.Is(err, os.ErrPermission) {
+		return true
+	}
+
+	message := strings.ToLower(err.Error())
+	for _, hint := range []string{
+		"permission denied",
+		"not authorized",
+		"insufficient privileges",
+		"operation not permitted",
+		"administrator privileges",
+		"must be root",
+		"run as root",
+	} {
+		if strings.Contains(message, hint) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (a *App) GetSystemProxyBypass() FlagResult {
 	log.Printf("GetSystemProxyBypass")
 
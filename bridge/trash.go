@@ -0,0 +1,443 @@
+package bridge
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Trashed files are kept in the freedesktop layout, files/<name> next to info/<name>.trashinfo,
+// both in data/.trash and in the system trash of Linux desktops.
+const (
+	trashInfoExt    = ".trashinfo"
+	trashDateLayout = "2006-01-02T15:04:05"
+	systemTrashID   = "system:"
+)
+
+type trashEntry struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Path      string `json:"path"`
+	DeletedAt int64  `json:"deletedAt"`
+	IsDir     bool   `json:"isDir"`
+	Size      int64  `json:"size"`
+	System    bool   `json:"system"`
+}
+
+func (a *App) MoveToTrash(path string) FlagResult {
+	log.Printf("MoveToTrash: %s", path)
+
+	fullPath, err := sandboxPath(path, sandboxRemove)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	id, err := moveToTrash(fullPath)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	return FlagResult{true, id}
+}
+
+// ListTrash, RestoreFromTrash and EmptyTrash take the key of InitSandbox, as they
+// reach into the trash that sandboxed callers must not see.
+func (a *App) ListTrash(key string) FlagResult {
+	log.Printf("ListTrash")
+
+	if err := checkSandboxAdmin(key); err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	if _, err := purgeTrash(trashMaxAge()); err != nil {
+		log.Printf("ListTrash: purge: %v", err)
+	}
+
+	entries := []trashEntry{}
+	for _, dir := range trashDirs() {
+		list, err := readTrash(dir)
+		if err != nil {
+			return FlagResult{false, err.Error()}
+		}
+		entries = append(entries, list...)
+	}
+
+	slices.SortFunc(entries, func(a, b trashEntry) int { return cmp.Compare(b.DeletedAt, a.DeletedAt) })
+
+	b, err := json.Marshal(entries)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	return FlagResult{true, string(b)}
+}
+
+// RestoreFromTrash moves an entry back to its original path, or to target if given.
+// Existing files are never overwritten.
+func (a *App) RestoreFromTrash(key string, id string, target string) FlagResult {
+	log.Printf("RestoreFromTrash: %s -> %s", id, target)
+
+	if err := checkSandboxAdmin(key); err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	dir, name := trashLocation(id)
+	if dir == "" || name == "" || strings.ContainsAny(name, `/\`) {
+		return FlagResult{false, "trash entry not found"}
+	}
+
+	entry, err := readTrashInfo(dir, name)
+	if err != nil {
+		return FlagResult{false, "trash entry not found"}
+	}
+
+	if target == "" {
+		target = entry.Path
+	}
+	targetPath, err := sandboxPath(target, sandboxWrite)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	if _, err := os.Lstat(targetPath); err == nil {
+		return FlagResult{false, targetPath + " already exists"}
+	}
+	if err := os.MkdirAll(filepath.Dir(targetPath), os.ModePerm); err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	if err := moveTree(filepath.Join(dir, "files", name), targetPath); err != nil {
+		return FlagResult{false, err.Error()}
+	}
+	_ = os.Remove(filepath.Join(dir, "info", name+trashInfoExt))
+
+	return FlagResult{true, targetPath}
+}
+
+// EmptyTrash deletes entries older than maxAge days, or all of them for 0,
+// and returns how many were deleted.
+func (a *App) EmptyTrash(key string, maxAge int) FlagResult {
+	log.Printf("EmptyTrash: %d", maxAge)
+
+	if err := checkSandboxAdmin(key); err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	n, err := purgeTrash(time.Duration(maxAge) * 24 * time.Hour)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	return FlagResult{true, strconv.Itoa(n)}
+}
+
+// moveToTrash prefers the system trash when configured and falls back to data/.trash,
+// e.g. when the file lives on another filesystem than the home directory.
+func moveToTrash(fullPath string) (string, error) {
+	dataTrash := resolvePath("data/.trash")
+	if pathWithin(dataTrash, fullPath) || pathWithin(fullPath, dataTrash) {
+		return "", errors.New("cannot move the trash or a directory containing it")
+	}
+	if _, err := os.Lstat(fullPath); err != nil {
+		return "", err
+	}
+
+	if _, err := purgeTrash(trashMaxAge()); err != nil {
+		log.Printf("MoveToTrash: purge: %v", err)
+	}
+
+	if dir := systemTrashDir(); dir != "" {
+		name, err := trashInto(dir, fullPath)
+		if err == nil {
+			return systemTrashID + name, nil
+		}
+		log.Printf("MoveToTrash %s: system trash: %v", fullPath, err)
+	}
+
+	return trashInto(dataTrash, fullPath)
+}
+
+func trashInto(dir string, fullPath string) (string, error) {
+	for _, sub := range []string{"files", "info"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0700); err != nil {
+			return "", err
+		}
+	}
+
+	info := fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+		(&url.URL{Path: filepath.ToSlash(fullPath)}).EscapedPath(), time.Now().Format(trashDateLayout))
+
+	// Creating the info file exclusively reserves the name against concurrent deletes.
+	base := filepath.Base(fullPath)
+	for i := 1; ; i++ {
+		name := base
+		if i > 1 {
+			name = base + "." + strconv.Itoa(i)
+		}
+
+		infoPath := filepath.Join(dir, "info", name+trashInfoExt)
+		f, err := os.OpenFile(infoPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+
+		_, err = f.WriteString(info)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err == nil {
+			if _, serr := os.Lstat(filepath.Join(dir, "files", name)); serr == nil {
+				// A leftover without info, keep looking.
+				_ = os.Remove(infoPath)
+				continue
+			}
+			err = moveTree(fullPath, filepath.Join(dir, "files", name))
+		}
+		if err != nil {
+			_ = os.Remove(infoPath)
+			return "", err
+		}
+
+		return name, nil
+	}
+}
+
+// moveTree renames src to dst, copying it over and removing the original when they
+// are on different filesystems.
+func moveTree(src string, dst string) error {
+	err := os.Rename(src, dst)
+	if err == nil || !isCrossDeviceError(err) {
+		return err
+	}
+
+	if err := copyTree(src, dst); err != nil {
+		_ = os.RemoveAll(dst)
+		return err
+	}
+
+	if err := os.RemoveAll(src); err != nil {
+		return fmt.Errorf("copied to %s but could not remove the original: %w", dst, err)
+	}
+
+	return nil
+}
+
+func copyTree(src string, dst string) error {
+	return filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			return os.Mkdir(target, info.Mode().Perm()|0700)
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			return copyRegularFile(p, target, info)
+		}
+
+		return fmt.Errorf("%s: cannot copy %s", p, info.Mode().Type())
+	})
+}
+
+func copyRegularFile(src string, dst string, info fs.FileInfo) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, in)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
+
+func readTrash(dir string) ([]trashEntry, error) {
+	infos, err := os.ReadDir(filepath.Join(dir, "info"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []trashEntry{}
+	for _, info := range infos {
+		name, ok := strings.CutSuffix(info.Name(), trashInfoExt)
+		if !ok {
+			continue
+		}
+		entry, err := readTrashInfo(dir, name)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func readTrashInfo(dir string, name string) (trashEntry, error) {
+	entry := trashEntry{ID: name, Name: name}
+	if dir != resolvePath("data/.trash") {
+		entry.ID = systemTrashID + name
+		entry.System = true
+	}
+
+	f, err := os.Open(filepath.Join(dir, "info", name+trashInfoExt))
+	if err != nil {
+		return entry, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), "=")
+		switch key {
+		case "Path":
+			if entry.Path, err = url.PathUnescape(value); err != nil {
+				return entry, err
+			}
+		case "DeletionDate":
+			if t, err := time.ParseInLocation(trashDateLayout, value, time.Local); err == nil {
+				entry.DeletedAt = t.UnixMilli()
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return entry, err
+	}
+	if entry.Path == "" {
+		return entry, errors.New("invalid trash info")
+	}
+
+	// The system trash is shared with every other application, only ours are listed.
+	if entry.System && !pathWithin(Env.BasePath, entry.Path) {
+		return entry, errors.New("not trashed by us")
+	}
+
+	stat, err := os.Lstat(filepath.Join(dir, "files", name))
+	if err != nil {
+		return entry, err
+	}
+	entry.IsDir = stat.IsDir()
+	entry.Size = stat.Size()
+
+	return entry, nil
+}
+
+// purgeTrash permanently deletes entries older than maxAge, all of them for 0.
+func purgeTrash(maxAge time.Duration) (int, error) {
+	if maxAge < 0 {
+		return 0, nil
+	}
+
+	deadline := time.Now().Add(-maxAge).UnixMilli()
+	n := 0
+
+	for _, dir := range trashDirs() {
+		entries, err := readTrash(dir)
+		if err != nil {
+			return n, err
+		}
+		for _, entry := range entries {
+			if maxAge > 0 && entry.DeletedAt > deadline {
+				continue
+			}
+			if err := os.RemoveAll(filepath.Join(dir, "files", entry.Name)); err != nil {
+				return n, err
+			}
+			_ = os.Remove(filepath.Join(dir, "info", entry.Name+trashInfoExt))
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+func trashDirs() []string {
+	dirs := []string{resolvePath("data/.trash")}
+	if dir := systemTrashDir(); dir != "" {
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+func trashLocation(id string) (dir string, name string) {
+	if name, ok := strings.CutPrefix(id, systemTrashID); ok {
+		return systemTrashDir(), name
+	}
+	return resolvePath("data/.trash"), id
+}
+
+// systemTrashDir is the freedesktop home trash, used on Linux when Config.Trash.System is set.
+func systemTrashDir() string {
+	if Env.OS != "linux" || !Config.Trash.System {
+		return ""
+	}
+
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+
+	return filepath.ToSlash(filepath.Join(dataHome, "Trash"))
+}
+
+// trashMaxAge is how long entries are kept before they are purged, negative to keep them forever.
+func trashMaxAge() time.Duration {
+	days := Config.Trash.MaxAge
+	if days == 0 {
+		days = 30
+	}
+	if days < 0 {
+		return -1
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
@@ -35,6 +35,13 @@ type RequestOptions struct {
 	FileField string
 	Sha256    string
 	Stream    string
+	Batch     BatchOptions
+}
+
+type BatchOptions struct {
+	Interval   int // milliseconds between emitted batches, 0 emits every item on its own
+	MaxSize    int // items per batch
+	BufferSize int // items buffered between flushes, the oldest are dropped beyond this
 }
 
 type ExecOptions struct {
@@ -43,6 +50,114 @@ type ExecOptions struct {
 	StopOutputKeyword string
 	WorkingDirectory  string
 	Env               map[string]string
+	EnvFile           string   // KEY=VALUE lines applied before Env
+	UnsetEnv          []string // inherited variables to drop, wildcards allowed, e.g. *_proxy
+	CleanEnv          bool     // inherit only essential variables and EnvAllowlist
+	EnvAllowlist      []string // inherited variables kept in a clean environment, wildcards allowed
+	ExpandEnv         bool     // expand ${VAR} in the path, args, WorkingDirectory, EnvFile and Env values
+	LogRotate         LogRotateOptions
+	LogParser         string // "" emits raw lines / sing-box emits parsed JSON entries
+	LogLevel          string // minimum level forwarded when LogParser is set
+	Batch             BatchOptions
+	ReadinessProbe    ProbeOptions
+	LivenessProbe     ProbeOptions
+	ProbeEvent        string
+	Limits            ResourceLimits
+	Credential        CredentialOptions
+}
+
+type PrivilegedExecOptions struct {
+	LogFile      string // receives the output, opened by the GUI rather than the helper
+	PidFile      string
+	Capabilities []string // subset of CAP_NET_ADMIN / CAP_NET_BIND_SERVICE / CAP_NET_RAW, default all, linux only
+}
+
+type RouteOptions struct {
+	Destination string // CIDR, e.g. 0.0.0.0/1 or ::/1
+	Gateway     string // next hop, a gateway or a device is required
+	Device      string // interface name, e.g. tun0 / utun3
+	Table       int    // linux routing table, 0 is main
+	Metric      int
+}
+
+type RouteRuleOptions struct {
+	IPv6     bool
+	From     string // source CIDR
+	To       string // destination CIDR
+	Fwmark   uint32
+	Priority int
+	Table    int // required
+}
+
+type FirewallRuleOptions struct {
+	Action      string // accept / drop / reject
+	Direction   string // in / out
+	IPv6        bool
+	Protocol    string // tcp / udp, empty for any
+	Port        int    // destination port, needs a protocol
+	Source      string // CIDR
+	Destination string // CIDR
+	Device      string // interface name, e.g. tun0 / utun3
+}
+
+type CredentialOptions struct {
+	User         string   // user name or numeric uid, empty keeps the current user
+	Group        string   // group name or numeric gid, defaults to the user's primary group
+	Capabilities []string // ambient capabilities, e.g. CAP_NET_ADMIN / CAP_NET_BIND_SERVICE / CAP_NET_RAW
+}
+
+type ResourceLimits struct {
+	MemoryMax    int64  // bytes, needs a cgroup
+	CPUQuota     int    // percent of one CPU, e.g. 50 or 200, needs a cgroup
+	MaxOpenFiles uint64 // RLIMIT_NOFILE
+	Nice         int    // scheduling priority, -20 to 19
+	Cgroup       bool   // place the process in its own cgroup v2 group when delegation allows it, implied by MemoryMax and CPUQuota
+}
+
+type LogRotateOptions struct {
+	MaxSize      int64 // bytes, rotate once the log grows beyond this size
+	MaxAge       int   // seconds, rotate once the log has been written to for this long
+	MaxBackups   int   // rotated files to keep, defaults to 3
+	Compress     bool  // gzip rotated files, the newest backup stays plain until the next rotation
+	KeepPrevious bool  // keep the previous run's log as a backup instead of truncating it
+}
+
+type ProbeOptions struct {
+	Type             string // tcp / http / log, empty disables the probe
+	Address          string // tcp: host:port / http: url
+	Status           int    // http: expected status code, defaults to 200
+	Pattern          string // log: regular expression matched against output lines
+	Interval         int    // milliseconds
+	Timeout          int    // seconds
+	FailureThreshold int    // liveness: consecutive failures before the process is unhealthy
+	Restart          bool   // liveness: stop an unhealthy supervised process so its restart policy applies
+}
+
+type SupervisorOptions struct {
+	RestartPolicy string // never / on-failure / always
+	MaxRestarts   int    // restarts allowed within RestartWindow before giving up
+	RestartWindow int    // seconds
+	BackoffMin    int    // milliseconds
+	BackoffMax    int    // milliseconds
+	StopTimeout   int    // seconds
+}
+
+type SamplerOptions struct {
+	Interval     int // milliseconds between samples, defaults to 1000
+	Capacity     int // samples kept in history, defaults to 300
+	SummaryEvery int // samples between emitted summaries, defaults to 10
+}
+
+type KillOptions struct {
+	Signals []string // sent in order to processes still alive, e.g. SIGINT / SIGTERM / SIGKILL
+	Timeout int      // seconds to wait after each signal
+}
+
+type SessionOptions struct {
+	Pty  bool   // run the process in a pseudo-terminal (unix only)
+	Rows int    // terminal rows, defaults to 24
+	Cols int    // terminal columns, defaults to 80
+	Mode string // Binary / Text, encoding of emitted output
 }
 
 type Range struct {
@@ -51,8 +166,70 @@ type Range struct {
 }
 
 type IOOptions struct {
-	Mode  string // Binary / Text
-	Range string // "start-end" / "start-" / "-end"
+	Mode        string // Binary / Text
+	Range       string // "start-end" / "start-" / "-end"
+	InPlace     bool   // overwrite whole files in place instead of replacing them atomically
+	Backups     int    // .bak generations kept of whole files before they are overwritten
+	Lock        string // shared / exclusive, held for the duration of the call
+	LockTimeout int    // milliseconds to wait for the lock, default 10000
+}
+
+type LockOptions struct {
+	Shared  bool
+	Timeout int // milliseconds to wait for the lock, default 10000
+}
+
+type FileHandleOptions struct {
+	Access      string // read / write / append / readwrite
+	IdleTimeout int    // seconds without calls before the handle is closed, default 60
+}
+
+type LocalFileOptions struct {
+	TTL int // seconds the URL stays valid, default 300
+}
+
+type ArchiveOptions struct {
+	Base     string   // directory entry names are relative to, defaults to the parent of each path
+	Include  []string // globs files must match, e.g. *.yaml / data/rulesets/*
+	Exclude  []string // globs of files and directories to leave out
+	Event    string   // progress event
+	Manifest string   // name of a sha256sum style manifest added to the archive, e.g. SHA256SUMS
+}
+
+type ExtractOptions struct {
+	StripComponents int      // leading path elements removed from entry names
+	Entries         []string // globs or directories selecting the entries to extract, empty extracts all
+	Strict          bool     // abort on the first failing entry and leave the output untouched
+}
+
+type ListDirOptions struct {
+	Recursive bool     // walk subdirectories
+	MaxDepth  int      // levels walked when Recursive, 0 is unlimited
+	Include   []string // globs files must match, e.g. *.srs / rulesets/*.json
+	Exclude   []string // globs of files and directories to leave out
+	SortBy    string   // name / size / modTime
+	Desc      bool
+	DirsFirst bool
+	Offset    int
+	Limit     int // entries returned, 0 returns all
+}
+
+type SearchOptions struct {
+	Regex       bool // pattern is a regular expression instead of a literal
+	IgnoreCase  bool
+	Include     []string // globs files must match, e.g. *.yaml / rulesets/*.json
+	Exclude     []string // globs of files and directories to leave out
+	MaxFileSize int64    // larger files are skipped, default 10MB
+	MaxResults  int      // matching lines returned, default 1000
+	Event       string   // receives the matches of each file
+	CancelId    string
+}
+
+type WatchOptions struct {
+	Recursive bool     // also watch every directory below the path
+	Include   []string // globs a changed path must match, e.g. *.yaml / rulesets/*.srs
+	Exclude   []string // globs of paths to ignore
+	Debounce  int      // milliseconds of quiet before changes are emitted, defaults to 100
 }
 
 type FlagResult struct {
@@ -85,16 +262,38 @@ type HTTPResult struct {
 }
 
 type AppConfig struct {
-	WindowStartState  int  `yaml:"windowStartState"`
-	WebviewGpuPolicy  int  `yaml:"webviewGpuPolicy"`
-	ContentProtection bool `yaml:"contentProtection"`
-	Width             int  `yaml:"width"`
-	Height            int  `yaml:"height"`
-	MultipleInstance  bool `yaml:"multipleInstance"`
-	RollingRelease    bool `yaml:"rollingRelease" default:"true"`
+	WindowStartState  int           `yaml:"windowStartState"`
+	WebviewGpuPolicy  int           `yaml:"webviewGpuPolicy"`
+	ContentProtection bool          `yaml:"contentProtection"`
+	Width             int           `yaml:"width"`
+	Height            int           `yaml:"height"`
+	MultipleInstance  bool          `yaml:"multipleInstance"`
+	RollingRelease    bool          `yaml:"rollingRelease" default:"true"`
+	Sandbox           SandboxConfig `yaml:"sandbox"`
+	Trash             TrashConfig   `yaml:"trash"`
 	StartHidden       bool
 }
 
+type SandboxConfig struct {
+	Roots         []string `yaml:"roots"`         // relative to BasePath, default: data
+	AllowAbsolute bool     `yaml:"allowAbsolute"` // absolute paths outside of the roots
+	Commands      []string `yaml:"commands"`      // commands that may be looked up in PATH
+}
+
+type TrashConfig struct {
+	Enabled bool `yaml:"enabled"` // RemoveFile moves to the trash instead of deleting
+	MaxAge  int  `yaml:"maxAge"`  // days entries are kept, default 30, negative for ever
+	System  bool `yaml:"system"`  // prefer the freedesktop trash on Linux
+}
+
+type SandboxOptions struct {
+	Name          string   // shown in denials
+	Roots         []string // default: the configured roots
+	AllowAbsolute bool
+	ReadOnly      bool
+	Commands      []string // looked up in PATH, must be in the configured ones
+}
+
 type TrayContent struct {
 	Icon    string `json:"icon,omitempty"`
 	Title   string `json:"title,omitempty"`
@@ -12,8 +12,26 @@ import (
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 )
 
+// utf8Tail returns the length of an incomplete UTF-8 sequence at the end of data.
+func utf8Tail(data []byte) int {
+	for i := 1; i <= min(utf8.UTFMax-1, len(data)); i++ {
+		b := data[len(data)-i]
+		if b < utf8.RuneSelf {
+			return 0
+		}
+		if utf8.RuneStart(b) {
+			if !utf8.FullRune(data[len(data)-i:]) {
+				return i
+			}
+			return 0
+		}
+	}
+	return 0
+}
+
 type requestTransportKey struct {
 	Proxy    string
 	Insecure bool
@@ -28,6 +46,21 @@ func resolvePath(path string) string {
 	return filepath.ToSlash(filepath.Clean(path))
 }
 
+// matchPathGlob tests patterns without a slash against the file name
+// and the others against the whole slash separated relative path.
+func matchPathGlob(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		target := filepath.Base(name)
+		if strings.Contains(pattern, "/") {
+			target = name
+		}
+		if ok, _ := filepath.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
 func requestProxy(proxyAddr string) func(*http.Request) (*url.URL, error) {
 	proxy := http.ProxyFromEnvironment
 
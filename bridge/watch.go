@@ -0,0 +1,228 @@
+package bridge
+
+import (
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+var (
+	watcherMap     sync.Map
+	watcherCounter atomic.Uint64
+)
+
+type pathWatcher struct {
+	root    string
+	file    string // set when a single file is watched through its directory
+	event   string
+	options WatchOptions
+	policy  *sandboxPolicy
+	watcher *fsnotify.Watcher
+
+	mu      sync.Mutex
+	pending map[string]string
+	timer   *time.Timer
+}
+
+type watchEvent struct {
+	Path string `json:"path"`
+	Op   string `json:"op"`
+}
+
+func (a *App) WatchPath(path string, event string, options WatchOptions) FlagResult {
+	log.Printf("WatchPath: %s %s %v", path, event, options)
+
+	policy, fullPath, err := sandboxResolve(path, sandboxRead)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	stat, err := os.Stat(fullPath)
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	if options.Debounce <= 0 {
+		options.Debounce = 100
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	w := &pathWatcher{
+		root:    fullPath,
+		event:   event,
+		options: options,
+		policy:  policy,
+		watcher: watcher,
+		pending: map[string]string{},
+	}
+
+	if stat.IsDir() {
+		err = w.add(fullPath)
+	} else {
+		// Editors and atomic writes replace files by renaming over them, which a watch
+		// on the file itself would not survive.
+		w.root = filepath.ToSlash(filepath.Dir(fullPath))
+		w.file = fullPath
+		err = watcher.Add(w.root)
+	}
+	if err != nil {
+		watcher.Close()
+		return FlagResult{false, err.Error()}
+	}
+
+	id := "watch-" + strconv.FormatUint(watcherCounter.Add(1), 10)
+	watcherMap.Store(id, w)
+
+	go w.run(a)
+
+	return FlagResult{true, id}
+}
+
+func (a *App) UnwatchPath(id string) FlagResult {
+	log.Printf("UnwatchPath: %s", id)
+
+	value, ok := watcherMap.LoadAndDelete(id)
+	if !ok {
+		return FlagResult{false, "watcher not found"}
+	}
+
+	if err := value.(*pathWatcher).watcher.Close(); err != nil {
+		return FlagResult{false, err.Error()}
+	}
+
+	return FlagResult{true, "Success"}
+}
+
+// add watches dir, and every directory below it for recursive watches.
+func (w *pathWatcher) add(dir string) error {
+	if !w.options.Recursive {
+		return w.watcher.Add(dir)
+	}
+
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Directories can disappear while we walk them.
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != dir && w.excluded(path) {
+			return filepath.SkipDir
+		}
+		return w.watcher.Add(path)
+	})
+}
+
+func (w *pathWatcher) run(a *App) {
+	for {
+		select {
+		case ev, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			w.handle(a, ev)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("WatchPath %s: %v", w.root, err)
+		}
+	}
+}
+
+func (w *pathWatcher) handle(a *App, ev fsnotify.Event) {
+	path := filepath.ToSlash(ev.Name)
+
+	if w.file != "" && path != w.file {
+		return
+	}
+
+	if w.options.Recursive && ev.Has(fsnotify.Create) {
+		if stat, err := os.Lstat(path); err == nil && stat.IsDir() {
+			if err := w.add(path); err != nil {
+				log.Printf("WatchPath %s: %v", path, err)
+			}
+		}
+	}
+
+	op := ""
+	switch {
+	case ev.Has(fsnotify.Create):
+		op = "create"
+	case ev.Has(fsnotify.Write):
+		op = "write"
+	case ev.Has(fsnotify.Remove):
+		op = "remove"
+	case ev.Has(fsnotify.Rename):
+		op = "rename"
+	default:
+		return
+	}
+
+	if w.file == "" && (!w.included(path) || w.excluded(path)) {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// Within one debounce window a created file stays created however often it is written.
+	if prev, ok := w.pending[path]; !ok || !(prev == "create" && op == "write") {
+		w.pending[path] = op
+	}
+
+	if w.timer == nil {
+		w.timer = time.AfterFunc(time.Duration(w.options.Debounce)*time.Millisecond, func() { w.flush(a) })
+	} else {
+		w.timer.Reset(time.Duration(w.options.Debounce) * time.Millisecond)
+	}
+}
+
+func (w *pathWatcher) flush(a *App) {
+	w.mu.Lock()
+	events := make([]watchEvent, 0, len(w.pending))
+	for path, op := range w.pending {
+		events = append(events, watchEvent{path, op})
+	}
+	w.pending = map[string]string{}
+	w.timer = nil
+	w.mu.Unlock()
+
+	if len(events) > 0 && w.event != "" {
+		runtime.EventsEmit(a.Ctx, w.event, events)
+	}
+}
+
+func (w *pathWatcher) included(path string) bool {
+	return len(w.options.Include) == 0 || matchPathGlob(w.options.Include, w.rel(path))
+}
+
+func (w *pathWatcher) excluded(path string) bool {
+	return matchPathGlob(w.options.Exclude, w.rel(path)) || w.policy.hides(path)
+}
+
+// rel returns path relative to the watched directory, slash separated.
+func (w *pathWatcher) rel(path string) string {
+	rel, err := filepath.Rel(w.root, path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
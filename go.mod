@@ -3,10 +3,14 @@ module guiforcores
 go 1.26
 
 require (
+	github.com/creack/pty v1.1.24
 	github.com/energye/systray v1.0.3
+	github.com/fsnotify/fsnotify v1.9.0
+	github.com/klauspost/compress v1.20.1
 	github.com/oschwald/geoip2-golang v1.13.0
 	github.com/pkg/browser v0.0.0-20240102092130-5ac0b6a4141c
 	github.com/shirou/gopsutil/v3 v3.24.5
+	github.com/ulikunitz/xz v0.5.17
 	github.com/wailsapp/wails/v2 v2.13.0
 	golang.org/x/sys v0.47.0
 	gopkg.in/yaml.v3 v3.0.1
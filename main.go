@@ -4,6 +4,7 @@ import (
 	"context"
 	"embed"
 	"guiforcores/bridge"
+	"os"
 	"time"
 
 	"github.com/wailsapp/wails/v2"
@@ -23,6 +24,22 @@ var assets embed.FS
 var icon []byte
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "helper" {
+		if err := bridge.RunHelper(os.Args[2:]); err != nil {
+			println("Error:", err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "limits" {
+		if err := bridge.RunWithLimits(os.Args[2:]); err != nil {
+			println("Error:", err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
 	app := bridge.CreateApp(assets)
 
 	trayStart, trayEnd := bridge.CreateTray(app, icon)
@@ -67,7 +84,7 @@ func main() {
 		},
 		AssetServer: &assetserver.Options{
 			Assets:     assets,
-			Middleware: bridge.RollingRelease,
+			Middleware: assetserver.ChainMiddleware(bridge.LocalFiles, bridge.RollingRelease),
 		},
 		SingleInstanceLock: &options.SingleInstanceLock{
 			UniqueId: func() string {